@@ -0,0 +1,108 @@
+// Copyright (c) 2020 Aritz Olea
+// This file is part of Simplepipe <https://github.com/aritzz/simplepipe>
+//
+// Simplepipe is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Simplepipe is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Simplepipe.  If not, see <https://www.gnu.org/licenses/>.
+
+package data
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// CheckValue Check that a value meets the type and constraints of an input
+func (input PipelineInput) CheckValue(value string) error {
+	return input.CheckValueIn(value, "")
+}
+
+// CheckValueIn Check a value as CheckValue does, relative file
+// paths are looked up in dir (the current directory if empty)
+func (input PipelineInput) CheckValueIn(value string, dir string) error {
+	switch input.Type {
+	case INPUT_INT:
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			return errors.New("Invalid value for " + input.Name + ": " + value + " is not an integer")
+		}
+		if input.HasRange && (number < input.Min || number > input.Max) {
+			return errors.New("Invalid value for " + input.Name + ": " + value + " is not in range " + input.RangeString())
+		}
+	case INPUT_ENUM:
+		for _, option := range input.Enum {
+			if value == option {
+				return nil
+			}
+		}
+		return errors.New("Invalid value for " + input.Name + ": " + value + " is not one of " + strings.Join(input.Enum, ", "))
+	case INPUT_FILE:
+		if input.Exists {
+			path := value
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(dir, path)
+			}
+			if _, err := os.Stat(path); err != nil {
+				return errors.New("Invalid value for " + input.Name + ": file " + value + " does not exist")
+			}
+		}
+	}
+
+	return nil
+}
+
+// String Get the name of an input type
+func (inputType InputType) String() string {
+	switch inputType {
+	case INPUT_INT:
+		return "int"
+	case INPUT_ENUM:
+		return "enum"
+	case INPUT_FILE:
+		return "file"
+	}
+	return "string"
+}
+
+// String Get the name of an input source
+func (source InputSource) String() string {
+	switch source {
+	case SOURCE_ARGS:
+		return "args"
+	case SOURCE_INPUT_FILE:
+		return "input file"
+	case SOURCE_ENV_FILE:
+		return "env file"
+	case SOURCE_ENV:
+		return "env"
+	case SOURCE_STDIN:
+		return "stdin"
+	case SOURCE_PROMPT:
+		return "prompt"
+	case SOURCE_DEFAULT:
+		return "default"
+	}
+	return "missing"
+}
+
+// MarshalText Encode an input source as its name
+func (source InputSource) MarshalText() ([]byte, error) {
+	return []byte(source.String()), nil
+}
+
+// RangeString Get the range of an int input as min..max
+func (input PipelineInput) RangeString() string {
+	return strconv.Itoa(input.Min) + ".." + strconv.Itoa(input.Max)
+}
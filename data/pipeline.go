@@ -24,51 +24,315 @@ const (
 	TYPE_ASSIGN ExecutionType = iota
 	TYPE_EXECASSIGN
 	TYPE_EXEC
+	TYPE_IF
+	TYPE_FOREACH
+	TYPE_PARALLEL
+	TYPE_TRY
+	TYPE_DEFER
+)
+
+const (
+	MODE_DIRECT ExecutionMode = iota
+	MODE_SHELL
 )
 
 type ExecutionType int
 
+const (
+	COND_EQUAL ConditionType = iota
+	COND_NOTEQUAL
+	COND_EMPTY
+	COND_EXEC
+)
+
+const (
+	LOOP_LIST LoopType = iota
+	LOOP_EXEC
+	LOOP_GLOB
+)
+
+const (
+	INPUT_STRING InputType = iota
+	INPUT_INT
+	INPUT_ENUM
+	INPUT_FILE
+)
+
+const (
+	SOURCE_NONE InputSource = iota
+	SOURCE_ARGS
+	SOURCE_INPUT_FILE
+	SOURCE_ENV_FILE
+	SOURCE_ENV
+	SOURCE_STDIN
+	SOURCE_PROMPT
+	SOURCE_DEFAULT
+)
+
+const (
+	ERROR_NONE ErrorKind = iota
+	ERROR_FAILED
+	ERROR_TIMEOUT
+	ERROR_CANCELLED
+)
+
+const (
+	BACKOFF_NONE BackoffType = iota
+	BACKOFF_FIXED
+	BACKOFF_EXPONENTIAL
+)
+
+type ExecutionMode int
+
+type ErrorKind int
+
+type BackoffType int
+
+type ConditionType int
+
+type LoopType int
+
+type InputType int
+
+type InputSource int
+
 //
 // Pipeline related (before processing)
 //
 
+// Pipeline is a loaded pipeline file. Every parsed element has the
+// Position where it starts, DeclarationPos has the ones of Declaration.
+// Description is the comment block at the start of the file
 type Pipeline struct {
-	Name        string
-	Input       []PipelineInput
-	Declaration map[string]string
-	Output      PipelineOutput
-	Execution   []PipelineExecution
+	Pos            Position
+	Name           string
+	Description    string
+	Input          []PipelineInput
+	Declaration    map[string]string
+	DeclarationPos map[string]Position
+	Shell          []string
+	Timeout        time.Duration
+	Random         []PipelineRandom
+	Temp           []PipelineTemp
+	KeepTemp       bool
+	Output         PipelineOutput
+	Execution      PipelineBlock
 }
 
+// PipelineInput is a read declaration. Value is set before the pipeline
+// runs, Source tells where it came from. Inputs with HasDefault are
+// optional. Min and Max are only checked with HasRange, Enum only with
+// INPUT_ENUM and Exists only with INPUT_FILE. Env and Stdin are sources
+// used when the value is not given by arguments or files. Secret values
+// are not echoed when prompted nor printed
 type PipelineInput struct {
-	Name  string
-	Value string
+	Pos         Position
+	Name        string
+	Value       string
+	Source      InputSource
+	Description string
+	Type        InputType
+	HasRange    bool
+	Min         int
+	Max         int
+	Enum        []string
+	Exists      bool
+	Env         string
+	Stdin       bool
+	Secret      bool
+	HasDefault  bool
+	Default     string
+}
+
+// PipelineRandom is a rand declaration, its value is generated
+// when the pipeline starts
+type PipelineRandom struct {
+	Pos      Position
+	Name     string
+	Length   int
+	Alphabet string
 }
 
+// PipelineTemp is a temporary file or directory, created
+// when the pipeline starts
+type PipelineTemp struct {
+	Pos    Position
+	Name   string
+	Dir    bool
+	Suffix string
+}
+
+// PipelineBlock is a list of steps, executed in order
+type PipelineBlock []PipelineExecution
+
+// PipelineExecution is a step. Block steps (like TYPE_IF) hold
+// their nested steps, so a pipeline is a tree of blocks
 type PipelineExecution struct {
-	Type    ExecutionType
-	Command string
-	Output  string
+	Pos          Position
+	Type         ExecutionType
+	Command      string
+	Args         []string
+	Mode         ExecutionMode
+	Output       string
+	ErrOutput    string
+	CodeOutput   string
+	Timeout      time.Duration
+	Retry        *PipelineRetry
+	IgnoreErrors bool
+	Branches     []PipelineBranch
+	Loop         *PipelineLoop
+	Parallel     *PipelineParallel
+	Try          *PipelineTry
+	Body         PipelineBlock
 }
 
+// PipelineBranch is a conditional block, Condition is nil for else
+type PipelineBranch struct {
+	Pos       Position
+	Condition *PipelineCondition
+	Body      PipelineBlock
+}
+
+// PipelineCondition is a test used by if blocks. Comparisons use
+// Left and Right, COND_EMPTY uses Left and COND_EXEC runs Exec
+type PipelineCondition struct {
+	Pos    Position
+	Type   ConditionType
+	Negate bool
+	Left   string
+	Right  string
+	Exec   PipelineExecution
+}
+
+// PipelineOutput is the end of a pipeline and the values it returns,
+// in order. Structured is set when they are written as return { ... }
 type PipelineOutput struct {
-	Defined bool
-	Value   string
+	Pos        Position
+	Defined    bool
+	Structured bool
+	Values     []PipelineOutputValue
+}
+
+// PipelineOutputValue is a returned value. Value is a template,
+// end varname returns $varname with the variable name
+type PipelineOutputValue struct {
+	Name  string
+	Value string
+}
+
+// PipelineLoop is the item source of a foreach block. LOOP_LIST uses
+// Items, LOOP_GLOB uses Items[0] as pattern and LOOP_EXEC runs Exec
+type PipelineLoop struct {
+	Pos   Position
+	Type  LoopType
+	Items []string
+	Exec  PipelineExecution
+}
+
+// PipelineRetry is the retry policy of a step. A failed step runs again
+// up to Retries more times, waiting between attempts as set by Backoff.
+// With ExitCodes set, only those exit codes are retried
+type PipelineRetry struct {
+	Retries   int
+	Backoff   BackoffType
+	MinDelay  time.Duration
+	MaxDelay  time.Duration
+	ExitCodes []int
+}
+
+// PipelineParallel are the settings of a parallel block. Max limits
+// how many steps run at once (0 is no limit). On failure the other
+// steps are cancelled, unless WaitAll is set
+type PipelineParallel struct {
+	Max     int
+	WaitAll bool
+}
+
+// PipelineTry are the error handling blocks of a try block. If a step in
+// the try block fails, Catch runs with the error message and exit code
+// in the CatchMessage and CatchCode variables. Finally always runs
+type PipelineTry struct {
+	CatchPos     Position
+	FinallyPos   Position
+	HasCatch     bool
+	CatchMessage string
+	CatchCode    string
+	Catch        PipelineBlock
+	Finally      PipelineBlock
 }
 
 //
 // Pipeline results (after processing)
 //
 
+// PipelineResult is the result of a run, Deferred holds the
+// deferred commands in the order they ran. TempDir is only set
+// when temporary files have been kept. Output has the returned
+// values in the order they are declared. Cancelled is set when
+// the run was interrupted, the result is partial then
 type PipelineResult struct {
-	Variables map[string]string
-	Time      time.Duration
-	ExecStep  []PipelineResultExecStep
-	Output    string
+	Name      string                   `json:"name"`
+	Inputs    []PipelineResultInput    `json:"inputs"`
+	Variables map[string]string        `json:"variables"`
+	Cancelled bool                     `json:"cancelled"`
+	Start     time.Time                `json:"start"`
+	End       time.Time                `json:"end"`
+	Time      time.Duration            `json:"duration_ns"`
+	ExecStep  []PipelineResultExecStep `json:"steps"`
+	Deferred  []PipelineResultExecStep `json:"deferred"`
+	TempDir   string                   `json:"temp_dir,omitempty"`
+	Output    []PipelineResultOutput   `json:"output"`
+}
+
+// PipelineResultInput is the value an input had in a run
+type PipelineResultInput struct {
+	Name   string      `json:"name"`
+	Value  string      `json:"value"`
+	Source InputSource `json:"source"`
+	Secret bool        `json:"secret,omitempty"`
 }
 
+// PipelineResultOutput is a value returned by a pipeline
+type PipelineResultOutput struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// PipelineResultExecStep is the result of a step. Command has its
+// variables replaced, except for shell steps that keep the script as
+// written. Iteration holds the indexes of the enclosing foreach blocks,
+// outermost first. Number is the one its streamed output is prefixed
+// with, 0 if it ran no command. Stdout and Stderr are capped, StdoutBytes
+// and StderrBytes are the whole sizes written. Signal is set if a signal
+// ended the command. Ignored is set for errors that didn't stop the
+// pipeline (ignore-errors or catch)
 type PipelineResultExecStep struct {
-	Command  string
-	Error    string
-	ExecTime time.Duration
+	Number      int                     `json:"number,omitempty"`
+	Command     string                  `json:"command"`
+	Mode        ExecutionMode           `json:"mode"`
+	Skipped     bool                    `json:"skipped,omitempty"`
+	Ignored     bool                    `json:"ignored,omitempty"`
+	Iteration   []int                   `json:"iteration,omitempty"`
+	Error       string                  `json:"error,omitempty"`
+	ErrorKind   ErrorKind               `json:"error_kind"`
+	ExitCode    int                     `json:"exit_code"`
+	Signal      string                  `json:"signal,omitempty"`
+	Stdout      string                  `json:"stdout"`
+	Stderr      string                  `json:"stderr"`
+	StdoutBytes int64                   `json:"stdout_bytes"`
+	StderrBytes int64                   `json:"stderr_bytes"`
+	Start       time.Time               `json:"start"`
+	End         time.Time               `json:"end"`
+	ExecTime    time.Duration           `json:"duration_ns"`
+	Attempts    []PipelineResultAttempt `json:"attempts,omitempty"`
+}
+
+// PipelineResultAttempt is a single run of a step command,
+// Stderr holds the last bytes written to standard error
+type PipelineResultAttempt struct {
+	ExitCode  int           `json:"exit_code"`
+	Error     string        `json:"error,omitempty"`
+	ErrorKind ErrorKind     `json:"error_kind"`
+	Stderr    string        `json:"stderr"`
+	ExecTime  time.Duration `json:"duration_ns"`
 }
@@ -0,0 +1,53 @@
+// Copyright (c) 2020 Aritz Olea
+// This file is part of Simplepipe <https://github.com/aritzz/simplepipe>
+//
+// Simplepipe is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Simplepipe is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Simplepipe.  If not, see <https://www.gnu.org/licenses/>.
+
+package data
+
+import (
+	"strconv"
+)
+
+// Position is a place in a pipeline file. Lines and columns start at 1,
+// columns count bytes. The zero Position is unknown
+type Position struct {
+	File   string
+	Line   int
+	Column int
+}
+
+// IsValid Check if the position is known
+func (pos Position) IsValid() bool {
+	return pos.Line > 0
+}
+
+// String Get the position as file:line:column
+func (pos Position) String() string {
+	text := pos.File
+	if !pos.IsValid() {
+		if len(text) == 0 {
+			return "-"
+		}
+		return text
+	}
+	if len(text) > 0 {
+		text += ":"
+	}
+	text += strconv.Itoa(pos.Line)
+	if pos.Column > 0 {
+		text += ":" + strconv.Itoa(pos.Column)
+	}
+	return text
+}
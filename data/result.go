@@ -0,0 +1,48 @@
+// Copyright (c) 2020 Aritz Olea
+// This file is part of Simplepipe <https://github.com/aritzz/simplepipe>
+//
+// Simplepipe is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Simplepipe is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Simplepipe.  If not, see <https://www.gnu.org/licenses/>.
+
+package data
+
+// String Get the name of an execution mode
+func (mode ExecutionMode) String() string {
+	if mode == MODE_SHELL {
+		return "shell"
+	}
+	return "direct"
+}
+
+// MarshalText Encode an execution mode as its name
+func (mode ExecutionMode) MarshalText() ([]byte, error) {
+	return []byte(mode.String()), nil
+}
+
+// String Get the name of an error kind
+func (kind ErrorKind) String() string {
+	switch kind {
+	case ERROR_FAILED:
+		return "failed"
+	case ERROR_TIMEOUT:
+		return "timeout"
+	case ERROR_CANCELLED:
+		return "cancelled"
+	}
+	return "none"
+}
+
+// MarshalText Encode an error kind as its name
+func (kind ErrorKind) MarshalText() ([]byte, error) {
+	return []byte(kind.String()), nil
+}
@@ -0,0 +1,129 @@
+// Copyright (c) 2020 Aritz Olea
+// This file is part of Simplepipe <https://github.com/aritzz/simplepipe>
+//
+// Simplepipe is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Simplepipe is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Simplepipe.  If not, see <https://www.gnu.org/licenses/>.
+
+package data
+
+import (
+	"errors"
+	"strings"
+)
+
+// Interpolate Replaces variable references in a template in a single pass.
+// $name and ${name} are replaced with the value given by lookup, and $$
+// writes a literal dollar. Replaced values are never scanned again.
+func Interpolate(template string, lookup func(string) (string, bool)) (string, error) {
+	var out strings.Builder
+
+	err := scanTemplate(template, func(literal string, name string) error {
+		if len(name) == 0 {
+			out.WriteString(literal)
+			return nil
+		}
+		value, ok := lookup(name)
+		if !ok {
+			return errors.New("Variable " + name + " is not declared")
+		}
+		out.WriteString(value)
+		return nil
+	})
+
+	return out.String(), err
+}
+
+// TemplateVars Gets the variable names referenced in a template, in order
+func TemplateVars(template string) ([]string, error) {
+	var names []string
+
+	err := scanTemplate(template, func(literal string, name string) error {
+		if len(name) > 0 {
+			names = append(names, name)
+		}
+		return nil
+	})
+
+	return names, err
+}
+
+// scanTemplate Walks a template calling fn for each literal piece or variable reference
+func scanTemplate(template string, fn func(literal string, name string) error) error {
+	start := 0
+
+	for i := 0; i < len(template); i++ {
+		if template[i] != '$' {
+			continue
+		}
+		if err := fn(template[start:i], ""); err != nil {
+			return err
+		}
+
+		if i+1 < len(template) && template[i+1] == '$' {
+			if err := fn("$", ""); err != nil {
+				return err
+			}
+			i++
+			start = i + 1
+			continue
+		}
+
+		var name string
+		if i+1 < len(template) && template[i+1] == '{' {
+			end := strings.IndexByte(template[i+2:], '}')
+			if end < 0 {
+				return errors.New("Unterminated variable reference in: " + template)
+			}
+			name = template[i+2 : i+2+end]
+			if !isVarName(name) {
+				return errors.New("Invalid variable name ${" + name + "} in: " + template)
+			}
+			i = i + 2 + end
+		} else {
+			end := i + 1
+			for end < len(template) && isVarChar(template[end]) {
+				end++
+			}
+			name = template[i+1 : end]
+			if len(name) == 0 {
+				return errors.New("Lone $ in: " + template + " (use $$ for a literal dollar)")
+			}
+			i = end - 1
+		}
+
+		if err := fn("", name); err != nil {
+			return err
+		}
+		start = i + 1
+	}
+
+	return fn(template[start:], "")
+}
+
+// isVarName Check if a string is a valid variable name
+func isVarName(name string) bool {
+	if len(name) == 0 {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		if !isVarChar(name[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// isVarChar Check if a byte can be part of a variable name
+func isVarChar(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
@@ -0,0 +1,88 @@
+// Copyright (c) 2020 Aritz Olea
+// This file is part of Simplepipe <https://github.com/aritzz/simplepipe>
+//
+// Simplepipe is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Simplepipe is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Simplepipe.  If not, see <https://www.gnu.org/licenses/>.
+
+package data
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestInterpolate(t *testing.T) {
+	vars := map[string]string{"a": "1", "b": "2", "ab": "12", "dollar": "$b", "empty": ""}
+	lookup := func(name string) (string, bool) {
+		value, ok := vars[name]
+		return value, ok
+	}
+
+	tests := []struct {
+		template string
+		want     string
+		ok       bool
+	}{
+		{"plain text", "plain text", true},
+		{"", "", true},
+		{"$a", "1", true},
+		{"x${a}y", "x1y", true},
+		{"$a$b", "12", true},
+		{"${a}${b}", "12", true},
+		{"$ab", "12", true},
+		{"${a}b", "1b", true},
+		{"$a.wav", "1.wav", true},
+		{"$empty-$a", "-1", true},
+		{"$$", "$", true},
+		{"$$a", "$a", true},
+		{"$$$a", "$1", true},
+		{"cost: 5$$", "cost: 5$", true},
+		{"$dollar", "$b", true},
+		{"$missing", "", false},
+		{"${missing}", "", false},
+		{"trailing $", "", false},
+		{"$ a", "", false},
+		{"${a", "", false},
+		{"${a-b}", "", false},
+		{"${}", "", false},
+	}
+
+	for _, test := range tests {
+		got, err := Interpolate(test.template, lookup)
+		if test.ok != (err == nil) {
+			t.Errorf("%q: error %v, want ok %v", test.template, err, test.ok)
+			continue
+		}
+		if test.ok && got != test.want {
+			t.Errorf("%q: got %q, want %q", test.template, got, test.want)
+		}
+	}
+}
+
+func TestTemplateVars(t *testing.T) {
+	tests := []struct {
+		template string
+		names    []string
+	}{
+		{"$a ${b} $$c $a", []string{"a", "b", "a"}},
+		{"$a$b", []string{"a", "b"}},
+		{"no vars $$", nil},
+	}
+
+	for _, test := range tests {
+		names, err := TemplateVars(test.template)
+		if err != nil || !reflect.DeepEqual(names, test.names) {
+			t.Errorf("%q: got %q, %v, want %q", test.template, names, err, test.names)
+		}
+	}
+}
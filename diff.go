@@ -0,0 +1,150 @@
+// Copyright (c) 2020 Aritz Olea
+// This file is part of Simplepipe <https://github.com/aritzz/simplepipe>
+//
+// Simplepipe is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Simplepipe is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Simplepipe.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// DIFF_CONTEXT is the number of unchanged lines around each change
+const DIFF_CONTEXT = 3
+
+// diffLine is a line of a diff, kind is ' ', '-' or '+'
+type diffLine struct {
+	kind byte
+	text string
+}
+
+// writeDiff Write a unified diff from a to b, with the names used in the
+// file headers. Nothing is written if both are equal
+func writeDiff(w io.Writer, nameA string, a string, nameB string, b string) {
+	lines := diffLines(splitLines(a), splitLines(b))
+
+	// Line numbers where each diff line is, in a and b
+	posA, posB := make([]int, len(lines)+1), make([]int, len(lines)+1)
+	changed := false
+	for i, line := range lines {
+		posA[i+1], posB[i+1] = posA[i], posB[i]
+		if line.kind != '+' {
+			posA[i+1]++
+		}
+		if line.kind != '-' {
+			posB[i+1]++
+		}
+		changed = changed || line.kind != ' '
+	}
+	if !changed {
+		return
+	}
+
+	fmt.Fprintf(w, "--- %s\n+++ %s\n", nameA, nameB)
+	for start := 0; start < len(lines); {
+		// Find the next change and the end of its hunk, changes closer
+		// than twice the context go in the same hunk
+		first := start
+		for first < len(lines) && lines[first].kind == ' ' {
+			first++
+		}
+		if first == len(lines) {
+			break
+		}
+		last := first
+		for i := first; i < len(lines) && i-last <= 2*DIFF_CONTEXT+1; i++ {
+			if lines[i].kind != ' ' {
+				last = i
+			}
+		}
+		from, to := first-DIFF_CONTEXT, last+DIFF_CONTEXT+1
+		if from < start {
+			from = start
+		}
+		if to > len(lines) {
+			to = len(lines)
+		}
+
+		fmt.Fprintf(w, "@@ -%s +%s @@\n", hunkRange(posA[from], posA[to]), hunkRange(posB[from], posB[to]))
+		for _, line := range lines[from:to] {
+			fmt.Fprintf(w, "%c%s", line.kind, line.text)
+			if !strings.HasSuffix(line.text, "\n") {
+				fmt.Fprint(w, "\n\\ No newline at end of file\n")
+			}
+		}
+		start = to
+	}
+}
+
+// diffLines Get the shortest list of removed and added lines that turns
+// a into b, from their longest common subsequence
+func diffLines(a []string, b []string) []diffLine {
+	common := make([][]int, len(a)+1)
+	for i := range common {
+		common[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				common[i][j] = common[i+1][j+1] + 1
+			case common[i+1][j] >= common[i][j+1]:
+				common[i][j] = common[i+1][j]
+			default:
+				common[i][j] = common[i][j+1]
+			}
+		}
+	}
+
+	var lines []diffLine
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, diffLine{' ', a[i]})
+			i++
+			j++
+		case j == len(b) || (i < len(a) && common[i+1][j] >= common[i][j+1]):
+			lines = append(lines, diffLine{'-', a[i]})
+			i++
+		default:
+			lines = append(lines, diffLine{'+', b[j]})
+			j++
+		}
+	}
+	return lines
+}
+
+// splitLines Split a text into lines, keeping their line breaks
+func splitLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// hunkRange Get the range of lines of a hunk header, from and to are
+// 0 based line numbers. Empty ranges start at the line before
+func hunkRange(from int, to int) string {
+	switch to - from {
+	case 0:
+		return fmt.Sprintf("%d,0", from)
+	case 1:
+		return fmt.Sprintf("%d", from+1)
+	}
+	return fmt.Sprintf("%d,%d", from+1, to-from)
+}
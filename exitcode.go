@@ -0,0 +1,104 @@
+// Copyright (c) 2020 Aritz Olea
+// This file is part of Simplepipe <https://github.com/aritzz/simplepipe>
+//
+// Simplepipe is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Simplepipe is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Simplepipe.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/aritzz/simplepipe/data"
+	"github.com/aritzz/simplepipe/pipe"
+)
+
+// Exit codes, a failed step exits with the code of its command. Usage
+// and load errors use the codes of sysexits.h, that commands seldom use
+const (
+	EXIT_OK        = 0
+	EXIT_FAILURE   = 1
+	EXIT_USAGE     = 64
+	EXIT_LOAD      = 65
+	EXIT_TIMEOUT   = 124
+	EXIT_INTERRUPT = 130
+)
+
+// EXIT_HELP describes the exit codes in the usage message
+const EXIT_HELP = `
+Exit status:
+  0    the pipeline finished
+  N    a step failed, N is the exit code of its command
+  1    a step failed without an exit code or with a reserved one,
+       or the pipeline failed otherwise
+  64   wrong flags, or inputs missing or not valid
+  65   the pipeline file could not be loaded
+  124  a step or the pipeline timed out
+  130  interrupted by SIGINT or SIGTERM
+Codes 64, 65, 124 and 130 are reserved, a step command that exits with
+one of them makes simplepipe exit with 1.
+`
+
+// printUsage Print the usage message of the run command
+func printUsage(flags *flag.FlagSet) {
+	output := flags.Output()
+	fmt.Fprintln(output, "Usage: simplepipe [flags] -pipeline file.pipe [inputs...]")
+	fmt.Fprintln(output, "       simplepipe validate|fmt|help [flags] file.pipe...")
+	fmt.Fprintln(output)
+	fmt.Fprintln(output, "Flags:")
+	flags.PrintDefaults()
+	fmt.Fprint(output, EXIT_HELP)
+}
+
+// parseErrorCode Get the exit code for an error parsing flags, the
+// flag set has already printed it with the usage message
+func parseErrorCode(err error) int {
+	if err == flag.ErrHelp {
+		return EXIT_OK
+	}
+	return EXIT_USAGE
+}
+
+// exitCode Get the exit code for the result of a pipeline run
+func exitCode(result data.PipelineResult, err error) int {
+	var input *pipe.InputError
+	var timeout *pipe.TimeoutError
+	var step *pipe.StepError
+
+	switch {
+	case result.Cancelled:
+		return EXIT_INTERRUPT
+	case err == nil:
+		return EXIT_OK
+	case errors.As(err, &input):
+		return EXIT_USAGE
+	case errors.As(err, &timeout):
+		return EXIT_TIMEOUT
+	case errors.As(err, &step) && step.ExitCode > 0 && !isReservedExit(step.ExitCode):
+		return step.ExitCode
+	}
+
+	return EXIT_FAILURE
+}
+
+// isReservedExit Check if an exit code has a meaning of its own,
+// so a step exiting with it can't pass it through
+func isReservedExit(code int) bool {
+	switch code {
+	case EXIT_USAGE, EXIT_LOAD, EXIT_TIMEOUT, EXIT_INTERRUPT:
+		return true
+	}
+	return false
+}
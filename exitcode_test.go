@@ -0,0 +1,52 @@
+// Copyright (c) 2020 Aritz Olea
+// This file is part of Simplepipe <https://github.com/aritzz/simplepipe>
+//
+// Simplepipe is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Simplepipe is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Simplepipe.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aritzz/simplepipe/data"
+	"github.com/aritzz/simplepipe/pipe"
+)
+
+func TestExitCode(t *testing.T) {
+	failed := errors.New("failed")
+	tests := []struct {
+		name   string
+		result data.PipelineResult
+		err    error
+		code   int
+	}{
+		{"finished", data.PipelineResult{}, nil, EXIT_OK},
+		{"cancelled", data.PipelineResult{Cancelled: true}, failed, EXIT_INTERRUPT},
+		{"input", data.PipelineResult{}, &pipe.InputError{}, EXIT_USAGE},
+		{"timeout", data.PipelineResult{}, &pipe.TimeoutError{}, EXIT_TIMEOUT},
+		{"step", data.PipelineResult{}, &pipe.StepError{ExitCode: 2, Err: failed}, 2},
+		{"step exiting 3", data.PipelineResult{}, &pipe.StepError{ExitCode: 3, Err: failed}, 3},
+		{"step without exit code", data.PipelineResult{}, &pipe.StepError{Signal: "killed", Err: failed}, EXIT_FAILURE},
+		{"step exiting with usage", data.PipelineResult{}, &pipe.StepError{ExitCode: EXIT_USAGE, Err: failed}, EXIT_FAILURE},
+		{"step exiting with load", data.PipelineResult{}, &pipe.StepError{ExitCode: EXIT_LOAD, Err: failed}, EXIT_FAILURE},
+		{"other", data.PipelineResult{}, failed, EXIT_FAILURE},
+	}
+
+	for _, test := range tests {
+		if code := exitCode(test.result, test.err); code != test.code {
+			t.Errorf("%s: got %d, want %d", test.name, code, test.code)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright (c) 2020 Aritz Olea
+// This file is part of Simplepipe <https://github.com/aritzz/simplepipe>
+//
+// Simplepipe is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Simplepipe is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Simplepipe.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/aritzz/simplepipe/load"
+)
+
+// runFormat Run the fmt subcommand, printing pipeline files in
+// canonical form. Returns the exit code
+func runFormat(args []string) int {
+	flags := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	write := flags.Bool("w", false, "write the result to the file instead of stdout")
+	diff := flags.Bool("d", false, "print a diff instead of the result")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: simplepipe fmt [-w] [-d] [file.pipe...]")
+		fmt.Fprintln(flags.Output(), "Without files, the pipeline is read from stdin.")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return parseErrorCode(err)
+	}
+
+	if flags.NArg() == 0 {
+		if *write {
+			flags.Usage()
+			return EXIT_USAGE
+		}
+		content, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return EXIT_LOAD
+		}
+		return formatContent("<stdin>", content, false, *diff)
+	}
+
+	ret := EXIT_OK
+	for _, file := range flags.Args() {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			ret = EXIT_LOAD
+			continue
+		}
+		if code := formatContent(file, content, *write, *diff); code != EXIT_OK {
+			ret = code
+		}
+	}
+
+	return ret
+}
+
+// formatContent Format a pipeline and write, diff or print it
+func formatContent(file string, content []byte, write bool, diff bool) int {
+	formatted, err := load.Format(file, content)
+	if diags, ok := err.(load.Diagnostics); ok {
+		fmt.Fprint(os.Stderr, diags.Format())
+		return EXIT_LOAD
+	} else if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return EXIT_LOAD
+	}
+
+	if diff {
+		writeDiff(os.Stdout, file+".orig", string(content), file, string(formatted))
+	}
+
+	if write {
+		if bytes.Equal(content, formatted) {
+			return EXIT_OK
+		}
+		info, err := os.Stat(file)
+		if err == nil {
+			err = ioutil.WriteFile(file, formatted, info.Mode())
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return EXIT_FAILURE
+		}
+	}
+
+	if !write && !diff {
+		os.Stdout.Write(formatted)
+	}
+
+	return EXIT_OK
+}
@@ -0,0 +1,214 @@
+// Copyright (c) 2020 Aritz Olea
+// This file is part of Simplepipe <https://github.com/aritzz/simplepipe>
+//
+// Simplepipe is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Simplepipe is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Simplepipe.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/aritzz/simplepipe/data"
+	"github.com/aritzz/simplepipe/load"
+)
+
+// helpPage is the usage page of a pipeline, as printed by help -format json
+type helpPage struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Inputs      []helpInput `json:"inputs"`
+	Returns     []string    `json:"returns,omitempty"`
+}
+
+// helpInput is an input in the usage page of a pipeline
+type helpInput struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Type        string   `json:"type"`
+	Required    bool     `json:"required"`
+	Default     *string  `json:"default,omitempty"`
+	Min         *int     `json:"min,omitempty"`
+	Max         *int     `json:"max,omitempty"`
+	Enum        []string `json:"enum,omitempty"`
+	Exists      bool     `json:"exists,omitempty"`
+	Env         string   `json:"env,omitempty"`
+	Stdin       bool     `json:"stdin,omitempty"`
+	Secret      bool     `json:"secret,omitempty"`
+}
+
+// runHelp Run the help subcommand, printing the usage page of a
+// pipeline. Returns the exit code
+func runHelp(args []string) int {
+	flags := flag.NewFlagSet("help", flag.ContinueOnError)
+	format := flags.String("format", "text", "output format: text or json")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: simplepipe help [-format text|json] file.pipe")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return parseErrorCode(err)
+	}
+
+	if flags.NArg() != 1 || (*format != "text" && *format != "json") {
+		flags.Usage()
+		return EXIT_USAGE
+	}
+
+	pipeline, err := load.ParseFile(flags.Arg(0))
+	if diags, ok := err.(load.Diagnostics); ok {
+		fmt.Fprint(os.Stderr, diags.Format())
+		return EXIT_LOAD
+	} else if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return EXIT_LOAD
+	}
+
+	page := getHelpPage(pipeline)
+	if *format == "json" {
+		output, _ := json.MarshalIndent(page, "", "  ")
+		fmt.Println(string(output))
+		return EXIT_OK
+	}
+
+	printHelpPage(page, flags.Arg(0))
+	return EXIT_OK
+}
+
+// getHelpPage Get the usage page of a pipeline
+func getHelpPage(pipeline data.Pipeline) helpPage {
+	page := helpPage{Name: pipeline.Name, Description: pipeline.Description, Inputs: []helpInput{}}
+	for _, output := range pipeline.Output.Values {
+		page.Returns = append(page.Returns, output.Name)
+	}
+
+	for _, input := range pipeline.Input {
+		entry := helpInput{Name: input.Name, Description: input.Description, Type: input.Type.String(), Required: !input.HasDefault}
+		if input.HasDefault {
+			value := input.Default
+			if input.Secret {
+				value = SECRET_MASK
+			}
+			entry.Default = &value
+		}
+		if input.HasRange {
+			min, max := input.Min, input.Max
+			entry.Min, entry.Max = &min, &max
+		}
+		entry.Enum = input.Enum
+		entry.Exists = input.Exists
+		entry.Env = input.Env
+		entry.Stdin = input.Stdin
+		entry.Secret = input.Secret
+		page.Inputs = append(page.Inputs, entry)
+	}
+
+	return page
+}
+
+// printHelpPage Print the usage page of a pipeline file
+func printHelpPage(page helpPage, file string) {
+	fmt.Println("Pipeline", page.Name)
+	if len(page.Description) > 0 {
+		fmt.Println()
+		for _, line := range strings.Split(page.Description, "\n") {
+			fmt.Println(strings.TrimRight("  "+line, " "))
+		}
+	}
+
+	fmt.Println()
+	fmt.Println("Usage:")
+	fmt.Println("  simplepipe -pipeline " + file + inputsUsage(page.Inputs))
+
+	if len(page.Inputs) > 0 {
+		fmt.Println()
+		fmt.Println("Inputs:")
+		table := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+		for _, input := range page.Inputs {
+			fmt.Fprintln(table, "  --"+input.Name+"\t"+helpType(input)+"\t"+helpRequired(input)+"\t"+input.Description)
+		}
+		table.Flush()
+	}
+
+	if len(page.Returns) > 0 {
+		fmt.Println()
+		fmt.Println("Returns:")
+		for _, name := range page.Returns {
+			fmt.Println("  " + name)
+		}
+	}
+}
+
+// inputsUsage Get the arguments that set the inputs of a pipeline, after
+// a -- so flags are not mixed with them. Required inputs are given in
+// order up to the first optional one, that would take the next value,
+// and by name after it
+func inputsUsage(inputs []helpInput) string {
+	if len(inputs) == 0 {
+		return ""
+	}
+
+	usage := " --"
+	positional := true
+	for _, input := range inputs {
+		switch {
+		case !input.Required:
+			positional = false
+			usage += " [--" + input.Name + " <value>]"
+		case positional:
+			usage += " <" + input.Name + ">"
+		default:
+			usage += " --" + input.Name + " <value>"
+		}
+	}
+	return usage
+}
+
+// helpType Get the type of an input with its constraints
+func helpType(input helpInput) string {
+	kind := input.Type
+	switch {
+	case input.Min != nil:
+		kind = fmt.Sprint(input.Type, " ", *input.Min, "..", *input.Max)
+	case len(input.Enum) > 0:
+		kind = strings.Join(input.Enum, "|")
+	case input.Exists:
+		kind = "existing file"
+	}
+
+	if input.Secret {
+		kind += ", secret"
+	}
+	return kind
+}
+
+// helpRequired Get if an input is required, or its default value,
+// and where it is read from besides arguments
+func helpRequired(input helpInput) string {
+	required := "required"
+	if input.Default != nil {
+		required = "default " + fmt.Sprintf("%q", *input.Default)
+	}
+
+	if len(input.Env) > 0 {
+		required += ", env " + input.Env
+	} else if input.Stdin {
+		required += ", stdin"
+	}
+	return required
+}
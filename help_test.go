@@ -0,0 +1,81 @@
+// Copyright (c) 2020 Aritz Olea
+// This file is part of Simplepipe <https://github.com/aritzz/simplepipe>
+//
+// Simplepipe is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Simplepipe is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Simplepipe.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/aritzz/simplepipe/load"
+)
+
+// TestHelpUsage Check that the usage printed by help runs the pipeline
+// when its placeholders are replaced, with and without optional inputs
+func TestHelpUsage(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs the test command")
+	}
+
+	dir, err := ioutil.TempDir("", "simplepipe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "usage.pipe")
+	source := `pipeline usage
+  read src "source file"
+  read bitrate int default 128
+  read dst
+begin
+  (test $src = value-src)
+  (test $dst = value-dst)
+end
+`
+	if err = ioutil.WriteFile(file, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+	pipeline, err := load.ParseFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	usage := "simplepipe -pipeline " + file + inputsUsage(getHelpPage(pipeline).Inputs)
+	if want := " -- <src> [--bitrate <value>] --dst <value>"; !strings.HasSuffix(usage, want) {
+		t.Fatalf("got usage %q, want it to end with %q", usage, want)
+	}
+
+	// Named inputs get their name as placeholder, bitrate needs a number
+	values := strings.NewReplacer("<src>", "value-src", "--bitrate <value>", "--bitrate 192", "--dst <value>", "--dst value-dst")
+	withOptional := values.Replace(strings.NewReplacer("[", "", "]", "").Replace(usage))
+	withoutOptional := values.Replace(regexp.MustCompile(`\s*\[[^]]*\]`).ReplaceAllString(usage, ""))
+
+	for _, command := range []string{withOptional, withoutOptional} {
+		if strings.Contains(command, "<") {
+			t.Fatalf("placeholder left in %q", command)
+		}
+		args := append([]string{"-quiet", "-outputonly", "-no-input"}, strings.Fields(command)[1:]...)
+		if code := runPipeline(args); code != EXIT_OK {
+			t.Errorf("%s: got exit code %d", command, code)
+		}
+	}
+}
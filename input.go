@@ -0,0 +1,79 @@
+// Copyright (c) 2020 Aritz Olea
+// This file is part of Simplepipe <https://github.com/aritzz/simplepipe>
+//
+// Simplepipe is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Simplepipe is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Simplepipe.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/aritzz/simplepipe/data"
+	"github.com/aritzz/simplepipe/pipe"
+)
+
+// SECRET_MASK is printed instead of the value of secret inputs
+const SECRET_MASK = "********"
+
+// loadInputs Set pipeline inputs from every source, in order: arguments,
+// input file, env file, and the environment or stdin
+func loadInputs(pipeline *data.Pipeline, args []string, inputFile string, envFile string, readStdin bool) error {
+	if err := pipe.SetInput(pipeline, args); err != nil {
+		return err
+	}
+	if len(inputFile) > 0 {
+		if err := pipe.SetInputFile(pipeline, inputFile); err != nil {
+			return err
+		}
+	}
+	if len(envFile) > 0 {
+		if err := pipe.SetEnvFile(pipeline, envFile); err != nil {
+			return err
+		}
+	}
+
+	if readStdin {
+		return pipe.SetInputEnv(pipeline, os.Stdin)
+	}
+	return pipe.SetInputEnv(pipeline, nil)
+}
+
+// printInputs Print the inputs of a pipeline with their value and
+// the source it came from
+func printInputs(pipeline data.Pipeline) {
+	required := 0
+	for _, input := range pipeline.Input {
+		if !input.HasDefault {
+			required++
+		}
+	}
+
+	fmt.Print("You must provide ", required, " argument(s):", inputsUsage(getHelpPage(pipeline).Inputs), "\n")
+
+	for _, input := range pipeline.Input {
+		switch {
+		case input.Secret && input.Source != data.SOURCE_NONE:
+			fmt.Printf("  %s = %s (%s)\n", input.Name, SECRET_MASK, input.Source)
+		case input.Source == data.SOURCE_ENV:
+			fmt.Printf("  %s = %q (env %s)\n", input.Name, input.Value, input.Env)
+		case input.Source != data.SOURCE_NONE:
+			fmt.Printf("  %s = %q (%s)\n", input.Name, input.Value, input.Source)
+		case input.Stdin:
+			fmt.Printf("  %s is read from stdin\n", input.Name)
+		default:
+			fmt.Printf("  %s is missing\n", input.Name)
+		}
+	}
+}
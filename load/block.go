@@ -0,0 +1,327 @@
+// Copyright (c) 2020 Aritz Olea
+// This file is part of Simplepipe <https://github.com/aritzz/simplepipe>
+//
+// Simplepipe is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Simplepipe is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Simplepipe.  If not, see <https://www.gnu.org/licenses/>.
+
+package load
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/aritzz/simplepipe/data"
+)
+
+// openBlock Block being loaded, waiting for its closing keyword
+type openBlock struct {
+	closing string
+	step    data.PipelineExecution
+	hasElse bool
+	section string
+}
+
+// blockStack Blocks being loaded, innermost last
+type blockStack []openBlock
+
+// appendStep Add a step to the innermost open block, or to the pipeline
+func (blocks *blockStack) appendStep(pipeline *data.Pipeline, step data.PipelineExecution) {
+	if len(*blocks) == 0 {
+		pipeline.Execution = append(pipeline.Execution, step)
+		return
+	}
+
+	top := &(*blocks)[len(*blocks)-1]
+	switch {
+	case top.step.Type == data.TYPE_IF:
+		branch := &top.step.Branches[len(top.step.Branches)-1]
+		branch.Body = append(branch.Body, step)
+	case top.section == "catch":
+		top.step.Try.Catch = append(top.step.Try.Catch, step)
+	case top.section == "finally":
+		top.step.Try.Finally = append(top.step.Try.Finally, step)
+	default:
+		top.step.Body = append(top.step.Body, step)
+	}
+}
+
+// push Open a new block
+func (blocks *blockStack) push(closing string, step data.PipelineExecution) {
+	*blocks = append(*blocks, openBlock{closing: closing, step: step})
+}
+
+// pop Close the innermost block, checking its closing keyword
+func (blocks *blockStack) pop(pipeline *data.Pipeline, closing string) error {
+	if len(*blocks) == 0 || (*blocks)[len(*blocks)-1].closing != closing {
+		return errors.New("Unexpected " + closing + " with no open block")
+	}
+
+	top := (*blocks)[len(*blocks)-1]
+	*blocks = (*blocks)[:len(*blocks)-1]
+	blocks.appendStep(pipeline, top.step)
+
+	return nil
+}
+
+// top Get the innermost block if it is closed by the given keyword
+func (blocks *blockStack) top(closing string) *openBlock {
+	if len(*blocks) == 0 || (*blocks)[len(*blocks)-1].closing != closing {
+		return nil
+	}
+	return &(*blocks)[len(*blocks)-1]
+}
+
+// checkClosed Check that there are no open blocks, returns
+// the position of the innermost open one
+func (blocks *blockStack) checkClosed() (data.Position, error) {
+	if len(*blocks) > 0 {
+		top := (*blocks)[len(*blocks)-1]
+		return top.step.Pos, errors.New("Missing " + top.closing)
+	}
+	return data.Position{}, nil
+}
+
+// closeAll Close every open block, so their steps can still be checked
+func (blocks *blockStack) closeAll(pipeline *data.Pipeline) {
+	for len(*blocks) > 0 {
+		blocks.pop(pipeline, (*blocks)[len(*blocks)-1].closing)
+	}
+}
+
+// Get block statement, returns false if line is not a block statement
+func getPipelineBlock(source sourceLine, pipeline *data.Pipeline, blocks *blockStack) (bool, error) {
+	line := source.Text
+
+	// Else if
+	elseif := regexp.MustCompile(`^else\s+if\s+(.+)$`)
+	if len(elseif.FindStringSubmatch(line)) == 2 {
+		block := blocks.top("endif")
+		if block == nil || block.hasElse {
+			return true, errors.New("Unexpected else if: " + line)
+		}
+		text := elseif.FindStringSubmatch(line)[1]
+		condition, err := getCondition(text, subPos(source.Pos, line, text), *pipeline)
+		block.step.Branches = append(block.step.Branches, data.PipelineBranch{Pos: source.Pos, Condition: &condition})
+		return true, err
+	}
+
+	// Else
+	if line == "else" {
+		block := blocks.top("endif")
+		if block == nil || block.hasElse {
+			return true, errors.New("Unexpected else")
+		}
+		block.hasElse = true
+		block.step.Branches = append(block.step.Branches, data.PipelineBranch{Pos: source.Pos})
+		return true, nil
+	}
+
+	// If
+	ifblock := regexp.MustCompile(`^if\s+(.+)$`)
+	if len(ifblock.FindStringSubmatch(line)) == 2 {
+		text := ifblock.FindStringSubmatch(line)[1]
+		condition, err := getCondition(text, subPos(source.Pos, line, text), *pipeline)
+		step := data.PipelineExecution{Pos: source.Pos, Type: data.TYPE_IF, Command: canonicalBlock(line)}
+		step.Branches = append(step.Branches, data.PipelineBranch{Pos: source.Pos, Condition: &condition})
+		blocks.push("endif", step)
+		return true, err
+	}
+
+	// End if
+	if line == "endif" {
+		return true, blocks.pop(pipeline, "endif")
+	}
+
+	// Foreach
+	foreach := regexp.MustCompile(`^foreach\s+(\w+)\s+in\s+(.+)$`)
+	if len(foreach.FindStringSubmatch(line)) == 3 {
+		text := foreach.FindStringSubmatch(line)[2]
+		loop, err := getLoop(text, subPos(source.Pos, line, text), *pipeline)
+		step := data.PipelineExecution{Pos: source.Pos, Type: data.TYPE_FOREACH, Command: canonicalBlock(line), Output: foreach.FindStringSubmatch(line)[1], Loop: &loop}
+		blocks.push("endfor", step)
+		return true, err
+	}
+
+	// End foreach
+	if line == "endfor" {
+		return true, blocks.pop(pipeline, "endfor")
+	}
+
+	// Parallel
+	parallel := regexp.MustCompile(`^parallel(\s+max\s+(\d+))?(\s+(failfast|waitall))?$`)
+	if len(parallel.FindStringSubmatch(line)) == 5 {
+		settings := data.PipelineParallel{WaitAll: parallel.FindStringSubmatch(line)[4] == "waitall"}
+		settings.Max, _ = strconv.Atoi(parallel.FindStringSubmatch(line)[2])
+		step := data.PipelineExecution{Pos: source.Pos, Type: data.TYPE_PARALLEL, Command: canonicalBlock(line), Parallel: &settings}
+		blocks.push("endparallel", step)
+		return true, nil
+	}
+
+	// End parallel
+	if line == "endparallel" {
+		return true, blocks.pop(pipeline, "endparallel")
+	}
+
+	// Try
+	if line == "try" {
+		step := data.PipelineExecution{Pos: source.Pos, Type: data.TYPE_TRY, Command: canonicalBlock(line), Try: &data.PipelineTry{}}
+		blocks.push("endtry", step)
+		return true, nil
+	}
+
+	// Catch, with optional variables for the error message and exit code
+	catch := regexp.MustCompile(`^catch(\s+(\w+)(\s*,\s*(\w+))?)?$`)
+	if len(catch.FindStringSubmatch(line)) == 5 {
+		block := blocks.top("endtry")
+		if block == nil || len(block.section) > 0 {
+			return true, errors.New("Unexpected catch: " + line)
+		}
+		block.section = "catch"
+		block.step.Try.CatchPos = source.Pos
+		block.step.Try.HasCatch = true
+		block.step.Try.CatchMessage = getOutputName(catch.FindStringSubmatch(line)[2])
+		block.step.Try.CatchCode = getOutputName(catch.FindStringSubmatch(line)[4])
+		return true, nil
+	}
+
+	// Finally
+	if line == "finally" {
+		block := blocks.top("endtry")
+		if block == nil || block.section == "finally" {
+			return true, errors.New("Unexpected finally")
+		}
+		block.section = "finally"
+		block.step.Try.FinallyPos = source.Pos
+		return true, nil
+	}
+
+	// End try
+	if line == "endtry" {
+		if block := blocks.top("endtry"); block != nil && len(block.section) == 0 {
+			return true, errors.New("Missing catch or finally before endtry")
+		}
+		return true, blocks.pop(pipeline, "endtry")
+	}
+
+	return false, nil
+}
+
+// Get foreach items: (command), glob "pattern" or a list of values
+func getLoop(text string, pos data.Position, pipeline data.Pipeline) (data.PipelineLoop, error) {
+	loop := data.PipelineLoop{Pos: pos}
+
+	// Command output lines
+	execData, ok, err := getExecStep(text, pipeline)
+	if ok {
+		execData.Pos = pos
+		loop.Type = data.LOOP_EXEC
+		loop.Exec = execData
+		if glob := unquotedGlob(execData.Command); err == nil && execData.Mode == data.MODE_DIRECT && len(glob) > 0 {
+			return loop, newSyntaxError("Commands without sh don't expand globs, use sh ("+execData.Command+") or glob \"pattern\"", glob)
+		}
+		return loop, err
+	}
+
+	words, err := splitCommand(text)
+	if err != nil {
+		return loop, err
+	}
+
+	// File glob
+	if words[0] == "glob" {
+		if len(words) != 2 {
+			return loop, newSyntaxError("Invalid glob, use glob \"pattern\"", text)
+		}
+		loop.Type = data.LOOP_GLOB
+		loop.Items = words[1:]
+		return loop, nil
+	}
+
+	loop.Type = data.LOOP_LIST
+	loop.Items = words
+	return loop, nil
+}
+
+// Get the first word of a command with glob characters out of quotes,
+// commands that are not run by the shell get them as they are
+func unquotedGlob(command string) string {
+	for _, word := range splitRaw(command) {
+		var quote byte
+		for i := 0; i < len(word); i++ {
+			switch c := word[i]; {
+			case quote == '"' && c == '\\':
+				i++
+			case quote != 0:
+				if c == quote {
+					quote = 0
+				}
+			case c == '\\':
+				i++
+			case c == '\'' || c == '"':
+				quote = c
+			case c == '*' || c == '?' || c == '[':
+				return word
+			}
+		}
+	}
+	return ""
+}
+
+// Get a condition: (command), empty $var, $a == $b or $a != $b,
+// optionally negated with !
+func getCondition(text string, pos data.Position, pipeline data.Pipeline) (data.PipelineCondition, error) {
+	condition := data.PipelineCondition{Pos: pos}
+	line := text
+	text = strings.TrimSpace(text)
+
+	if strings.HasPrefix(text, "!") {
+		condition.Negate = true
+		text = strings.TrimSpace(text[1:])
+	}
+
+	// Command exit status
+	execData, ok, err := getExecStep(text, pipeline)
+	if ok {
+		execData.Pos = subPos(pos, line, text)
+		condition.Type = data.COND_EXEC
+		condition.Exec = execData
+		return condition, err
+	}
+
+	words, err := splitCommand(text)
+	if err != nil {
+		return condition, err
+	}
+
+	// Empty variable
+	if len(words) == 2 && words[0] == "empty" {
+		condition.Type = data.COND_EMPTY
+		condition.Left = words[1]
+		return condition, nil
+	}
+
+	// Comparison
+	if len(words) == 3 && (words[1] == "==" || words[1] == "!=") {
+		condition.Type = data.COND_EQUAL
+		if words[1] == "!=" {
+			condition.Type = data.COND_NOTEQUAL
+		}
+		condition.Left = words[0]
+		condition.Right = words[2]
+		return condition, nil
+	}
+
+	return condition, newSyntaxError("Invalid condition", text)
+}
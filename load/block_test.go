@@ -0,0 +1,46 @@
+// Copyright (c) 2020 Aritz Olea
+// This file is part of Simplepipe <https://github.com/aritzz/simplepipe>
+//
+// Simplepipe is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Simplepipe is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Simplepipe.  If not, see <https://www.gnu.org/licenses/>.
+
+package load
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoopGlob(t *testing.T) {
+	tests := []struct {
+		source string
+		err    string
+	}{
+		{"foreach f in (ls *.wav)", "use sh (ls *.wav)"},
+		{"foreach f in (ls dir/file?.wav)", "use sh (ls dir/file?.wav)"},
+		{"foreach f in sh (ls *.wav)", ""},
+		{"foreach f in glob \"*.wav\"", ""},
+		{"foreach f in (find . -name '*.wav')", ""},
+		{"foreach f in (find . -name \"*.wav\" -o -name \\*.mp3)", ""},
+	}
+
+	for _, test := range tests {
+		_, err := ParseString("test.pipe", "pipeline loop\nbegin\n  "+test.source+"\n  endfor\nend\n")
+		switch {
+		case len(test.err) == 0 && err != nil:
+			t.Errorf("%s: unexpected error %v", test.source, err)
+		case len(test.err) > 0 && (err == nil || !strings.Contains(err.Error(), test.err)):
+			t.Errorf("%s: got error %v, want %q", test.source, err, test.err)
+		}
+	}
+}
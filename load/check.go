@@ -0,0 +1,156 @@
+// Copyright (c) 2020 Aritz Olea
+// This file is part of Simplepipe <https://github.com/aritzz/simplepipe>
+//
+// Simplepipe is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Simplepipe is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Simplepipe.  If not, see <https://www.gnu.org/licenses/>.
+
+package load
+
+import (
+	"github.com/aritzz/simplepipe/data"
+)
+
+// checkVariables Check that every variable used by the pipeline is declared
+func checkVariables(pipeline data.Pipeline, diags *diagnosticList) {
+	declared := make(map[string]bool)
+	for _, val := range pipeline.Input {
+		declared[val.Name] = true
+	}
+	for key := range pipeline.Declaration {
+		declared[key] = true
+	}
+
+	checkBlockVariables(pipeline.Execution, declared, diags)
+
+	for _, output := range pipeline.Output.Values {
+		checkTemplateVariables([]string{output.Value}, pipeline.Output.Pos, declared, diags)
+	}
+}
+
+// checkBlockVariables Check variables used by every step in a block
+func checkBlockVariables(block data.PipelineBlock, declared map[string]bool, diags *diagnosticList) {
+	for _, execItem := range block {
+		checkStepVariables(execItem, declared, diags)
+	}
+}
+
+// checkStepVariables Check variables used by a single step
+func checkStepVariables(execstep data.PipelineExecution, declared map[string]bool, diags *diagnosticList) {
+	if execstep.Type != data.TYPE_FOREACH {
+		for _, output := range []string{execstep.Output, execstep.ErrOutput, execstep.CodeOutput} {
+			if len(output) > 0 && !declared[output] {
+				diags.add(execstep.Pos, newSyntaxError("Variable is not declared", output))
+			}
+		}
+	}
+
+	switch execstep.Type {
+	case data.TYPE_ASSIGN:
+		if !declared[execstep.Command] {
+			diags.add(execstep.Pos, newSyntaxError("Variable is not declared", execstep.Command))
+		}
+	case data.TYPE_EXEC, data.TYPE_EXECASSIGN, data.TYPE_DEFER:
+		// Shell steps read variables from the environment
+		if execstep.Mode != data.MODE_SHELL {
+			checkTemplateVariables(execstep.Args, execstep.Pos, declared, diags)
+		}
+	case data.TYPE_IF:
+		for _, branch := range execstep.Branches {
+			checkConditionVariables(branch.Condition, declared, diags)
+			checkBlockVariables(branch.Body, declared, diags)
+		}
+	case data.TYPE_FOREACH:
+		checkLoopVariables(execstep, declared, diags)
+	case data.TYPE_PARALLEL:
+		checkBlockVariables(execstep.Body, declared, diags)
+	case data.TYPE_TRY:
+		checkTryVariables(execstep, declared, diags)
+	}
+}
+
+// checkLoopVariables Check variables used by a foreach block,
+// the loop variable is only declared inside the block
+func checkLoopVariables(execstep data.PipelineExecution, declared map[string]bool, diags *diagnosticList) {
+	if declared[execstep.Output] {
+		diags.add(execstep.Pos, newSyntaxError("Loop variable is already declared", execstep.Output))
+	}
+
+	if execstep.Loop.Type == data.LOOP_EXEC {
+		checkStepVariables(execstep.Loop.Exec, declared, diags)
+	} else {
+		checkTemplateVariables(execstep.Loop.Items, execstep.Loop.Pos, declared, diags)
+	}
+
+	scope := copyScope(declared)
+	scope[execstep.Output] = true
+
+	checkBlockVariables(execstep.Body, scope, diags)
+}
+
+// checkTryVariables Check variables used by a try block, the
+// error variables are only declared inside the catch block
+func checkTryVariables(execstep data.PipelineExecution, declared map[string]bool, diags *diagnosticList) {
+	checkBlockVariables(execstep.Body, declared, diags)
+
+	scope := copyScope(declared)
+	for _, name := range []string{execstep.Try.CatchMessage, execstep.Try.CatchCode} {
+		if len(name) == 0 {
+			continue
+		}
+		if declared[name] {
+			diags.add(execstep.Try.CatchPos, newSyntaxError("Catch variable is already declared", name))
+		}
+		scope[name] = true
+	}
+	checkBlockVariables(execstep.Try.Catch, scope, diags)
+	checkBlockVariables(execstep.Try.Finally, declared, diags)
+}
+
+// copyScope Get a copy of declared variables for a nested scope
+func copyScope(declared map[string]bool) map[string]bool {
+	scope := make(map[string]bool)
+	for key := range declared {
+		scope[key] = true
+	}
+	return scope
+}
+
+// checkConditionVariables Check variables used by an if condition
+func checkConditionVariables(condition *data.PipelineCondition, declared map[string]bool, diags *diagnosticList) {
+	switch {
+	case condition == nil:
+	case condition.Type == data.COND_EXEC:
+		checkStepVariables(condition.Exec, declared, diags)
+	case condition.Type == data.COND_EMPTY:
+		checkTemplateVariables([]string{condition.Left}, condition.Pos, declared, diags)
+	default:
+		checkTemplateVariables([]string{condition.Left, condition.Right}, condition.Pos, declared, diags)
+	}
+}
+
+// checkTemplateVariables Check variables referenced by a list of
+// templates, found in the element at pos
+func checkTemplateVariables(templates []string, pos data.Position, declared map[string]bool, diags *diagnosticList) {
+	for _, template := range templates {
+		names, err := data.TemplateVars(template)
+		if err != nil {
+			diags.add(pos, err)
+			continue
+		}
+		for _, name := range names {
+			if !declared[name] {
+				diags.add(pos, newSyntaxError("Variable is not declared", "$"+name))
+			}
+		}
+	}
+}
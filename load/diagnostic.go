@@ -0,0 +1,226 @@
+// Copyright (c) 2020 Aritz Olea
+// This file is part of Simplepipe <https://github.com/aritzz/simplepipe>
+//
+// Simplepipe is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Simplepipe is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Simplepipe.  If not, see <https://www.gnu.org/licenses/>.
+
+package load
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/aritzz/simplepipe/data"
+)
+
+// Severity of a diagnostic
+type Severity int
+
+const (
+	SEVERITY_ERROR Severity = iota
+	SEVERITY_WARNING
+)
+
+// String Get the severity name
+func (severity Severity) String() string {
+	if severity == SEVERITY_WARNING {
+		return "warning"
+	}
+	return "error"
+}
+
+// Diagnostic is an error found loading a pipeline file. Source is
+// the line where it was found
+type Diagnostic struct {
+	Pos      data.Position
+	Severity Severity
+	Message  string
+	Source   string
+}
+
+// Error Get the diagnostic as file:line:column: message
+func (diag Diagnostic) Error() string {
+	if diag.Severity == SEVERITY_WARNING {
+		return diag.Pos.String() + ": warning: " + diag.Message
+	}
+	return diag.Pos.String() + ": " + diag.Message
+}
+
+// Format Get the diagnostic with its source line and a caret
+// under the column
+func (diag Diagnostic) Format() string {
+	text := diag.Error() + "\n"
+	if len(diag.Source) == 0 || diag.Pos.Column < 1 {
+		return text
+	}
+
+	var caret strings.Builder
+	for i, char := range diag.Source {
+		if i >= diag.Pos.Column-1 {
+			break
+		}
+		if char == '\t' {
+			caret.WriteRune('\t')
+		} else {
+			caret.WriteRune(' ')
+		}
+	}
+
+	return text + diag.Source + "\n" + caret.String() + "^\n"
+}
+
+// Diagnostics is every error found loading a pipeline file, in
+// the order they were found
+type Diagnostics []Diagnostic
+
+// Error Get every diagnostic, one per line
+func (diags Diagnostics) Error() string {
+	lines := make([]string, len(diags))
+	for i, diag := range diags {
+		lines[i] = diag.Error()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Format Get every diagnostic with its source line and a caret
+func (diags Diagnostics) Format() string {
+	var text strings.Builder
+	for _, diag := range diags {
+		text.WriteString(diag.Format())
+	}
+	return text.String()
+}
+
+// syntaxError is an error about a token of a line, its
+// diagnostic points at the token
+type syntaxError struct {
+	message string
+	token   string
+}
+
+// newSyntaxError Create an error about a token
+func newSyntaxError(message string, token string) error {
+	return &syntaxError{message: message, token: token}
+}
+
+func (err *syntaxError) Error() string {
+	return err.message + ": " + err.token
+}
+
+// sourceLine is a line of a pipeline file, without its indentation
+type sourceLine struct {
+	Pos  data.Position
+	Text string
+}
+
+// diagnosticList Collects diagnostics while a file is loaded
+type diagnosticList struct {
+	source []string
+	list   Diagnostics
+}
+
+// newDiagnosticList Create a diagnostic list for the content of a file
+func newDiagnosticList(content string) *diagnosticList {
+	return &diagnosticList{source: strings.Split(content, "\n")}
+}
+
+// add Add an error found in the element starting at pos. Errors about
+// a token point at the first place it appears from pos
+func (diags *diagnosticList) add(pos data.Position, err error) {
+	diags.addSeverity(pos, SEVERITY_ERROR, err)
+}
+
+// warn Add a warning found in the element starting at pos
+func (diags *diagnosticList) warn(pos data.Position, err error) {
+	diags.addSeverity(pos, SEVERITY_WARNING, err)
+}
+
+// addSeverity Add a diagnostic found in the element starting at pos
+func (diags *diagnosticList) addSeverity(pos data.Position, severity Severity, err error) {
+	diag := Diagnostic{Pos: pos, Severity: severity, Message: err.Error()}
+	if pos.Line > 0 && pos.Line <= len(diags.source) {
+		diag.Source = strings.TrimRight(diags.source[pos.Line-1], "\r")
+	}
+
+	if synerr, ok := err.(*syntaxError); ok && pos.Column > 0 && pos.Column <= len(diag.Source) {
+		diag.Pos.Column += tokenOffset(diag.Source[pos.Column-1:], synerr.token)
+	}
+
+	diags.list = append(diags.list, diag)
+}
+
+// err Get the collected diagnostics, nil if there are none
+func (diags *diagnosticList) err() error {
+	if len(diags.list) == 0 {
+		return nil
+	}
+	return diags.sorted()
+}
+
+// sorted Get the collected diagnostics sorted by position
+func (diags *diagnosticList) sorted() Diagnostics {
+	sort.SliceStable(diags.list, func(i, j int) bool {
+		a, b := diags.list[i].Pos, diags.list[j].Pos
+		return a.Line < b.Line || (a.Line == b.Line && a.Column < b.Column)
+	})
+	return diags.list
+}
+
+// tokenOffset Get where a token starts in text, 0 if it is not found.
+// Variables like $name are also found as ${name}
+func tokenOffset(text string, token string) int {
+	if i := wordIndex(text, token); i >= 0 {
+		return i
+	}
+	if strings.HasPrefix(token, "$") {
+		if i := wordIndex(text, "${"+token[1:]+"}"); i >= 0 {
+			return i
+		}
+	}
+	return 0
+}
+
+// wordIndex Get the first index of token in text that is not
+// part of a longer word, -1 if there is none
+func wordIndex(text string, token string) int {
+	if len(token) == 0 {
+		return -1
+	}
+
+	for offset := 0; offset < len(text); {
+		i := strings.Index(text[offset:], token)
+		if i < 0 {
+			return -1
+		}
+		start, end := offset+i, offset+i+len(token)
+		if (start == 0 || !isWordByte(text[start-1]) || !isWordByte(token[0])) &&
+			(end == len(text) || !isWordByte(text[end]) || !isWordByte(token[len(token)-1])) {
+			return start
+		}
+		offset = start + 1
+	}
+	return -1
+}
+
+// isWordByte Check if a byte can be part of a variable name
+func isWordByte(char byte) bool {
+	return char == '_' || (char >= 'a' && char <= 'z') || (char >= 'A' && char <= 'Z') || (char >= '0' && char <= '9')
+}
+
+// subPos Get the position of text, that ends the line starting at pos
+func subPos(pos data.Position, line string, text string) data.Position {
+	if offset := len(line) - len(text); offset > 0 && strings.HasSuffix(line, text) {
+		pos.Column += offset
+	}
+	return pos
+}
@@ -0,0 +1,256 @@
+// Copyright (c) 2020 Aritz Olea
+// This file is part of Simplepipe <https://github.com/aritzz/simplepipe>
+//
+// Simplepipe is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Simplepipe is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Simplepipe.  If not, see <https://www.gnu.org/licenses/>.
+
+package load
+
+import (
+	"errors"
+	"strings"
+)
+
+// splitCommand Splits a step command into an argument list.
+// Words are separated by blanks. Single quotes keep their content as is,
+// double quotes allow \" \\ \$ and \` escapes, and a backslash outside
+// quotes escapes the next character. A dollar that is quoted or escaped is
+// written as $$ so it stays literal when variables are replaced.
+func splitCommand(command string) ([]string, error) {
+	var args []string
+	var word strings.Builder
+	inWord := false
+	runes := []rune(command)
+
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		switch {
+		case c == ' ' || c == '\t':
+			if inWord {
+				args = append(args, word.String())
+				word.Reset()
+				inWord = false
+			}
+		case c == '\\':
+			if i+1 >= len(runes) {
+				return args, errors.New("Trailing backslash in command: " + command)
+			}
+			i++
+			writeLiteral(&word, runes[i])
+			inWord = true
+		case c == '\'':
+			end := indexRune(runes, i+1, '\'')
+			if end < 0 {
+				return args, errors.New("Unterminated single quote in command: " + command)
+			}
+			word.WriteString(strings.ReplaceAll(string(runes[i+1:end]), "$", "$$"))
+			i = end
+			inWord = true
+		case c == '"':
+			closed := false
+			for i++; i < len(runes); i++ {
+				if runes[i] == '"' {
+					closed = true
+					break
+				}
+				if runes[i] == '\\' && i+1 < len(runes) && strings.ContainsRune("\"\\$`", runes[i+1]) {
+					i++
+					writeLiteral(&word, runes[i])
+					continue
+				}
+				word.WriteRune(runes[i])
+			}
+			if !closed {
+				return args, errors.New("Unterminated double quote in command: " + command)
+			}
+			inWord = true
+		default:
+			word.WriteRune(c)
+			inWord = true
+		}
+	}
+
+	if inWord {
+		args = append(args, word.String())
+	}
+
+	if len(args) == 0 {
+		return args, errors.New("Empty command")
+	}
+
+	return args, nil
+}
+
+// splitParens Splits a text starting with ( into the content up to
+// the matching ) and the rest. Quoted and escaped parens are not counted
+func splitParens(text string) (string, string, error) {
+	depth := 0
+	var quote byte
+
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote == '\'':
+			if c == '\'' {
+				quote = 0
+			}
+		case quote == '"':
+			if c == '\\' {
+				i++
+			} else if c == '"' {
+				quote = 0
+			}
+		case c == '\\':
+			i++
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return text[1:i], strings.TrimSpace(text[i+1:]), nil
+			}
+		}
+	}
+
+	return text, "", errors.New("Missing closing parenthesis: " + text)
+}
+
+// splitComment Splits a line into its code and a trailing comment, that
+// starts at a ; which is not quoted, escaped nor inside parens
+func splitComment(line string) (string, string) {
+	depth := 0
+	var quote byte
+
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote == '\'':
+			if c == '\'' {
+				quote = 0
+			}
+		case quote == '"':
+			if c == '\\' {
+				i++
+			} else if c == '"' {
+				quote = 0
+			}
+		case c == '\\':
+			i++
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')' && depth > 0:
+			depth--
+		case c == ';' && depth == 0:
+			return strings.TrimSpace(line[:i]), line[i:]
+		}
+	}
+
+	return line, ""
+}
+
+// writeLiteral Write an escaped rune to a word
+func writeLiteral(word *strings.Builder, r rune) {
+	if r == '$' {
+		word.WriteString("$$")
+		return
+	}
+	word.WriteRune(r)
+}
+
+// indexRune Get position of a rune starting from a given index, -1 if not found
+func indexRune(runes []rune, from int, r rune) int {
+	for i := from; i < len(runes); i++ {
+		if runes[i] == r {
+			return i
+		}
+	}
+	return -1
+}
+
+// splitFields Splits a text at every sep that is not quoted or
+// escaped, fields are kept as written
+func splitFields(text string, sep byte) []string {
+	var fields []string
+	start := 0
+
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case sep:
+			fields = append(fields, text[start:i])
+			start = i + 1
+		case '\\':
+			i++
+		case '\'':
+			if end := strings.IndexByte(text[i+1:], '\''); end >= 0 {
+				i += end + 1
+			} else {
+				i = len(text)
+			}
+		case '"':
+			for i++; i < len(text) && text[i] != '"'; i++ {
+				if text[i] == '\\' {
+					i++
+				}
+			}
+		}
+	}
+
+	return append(fields, text[start:])
+}
+
+// splitRaw Splits a text into words as splitCommand does, but keeps
+// every word as written, with its quotes and escapes
+func splitRaw(text string) []string {
+	var words []string
+	start := -1
+
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		if c == ' ' || c == '\t' {
+			if start >= 0 {
+				words = append(words, text[start:i])
+				start = -1
+			}
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+		switch c {
+		case '\\':
+			i++
+		case '\'':
+			if end := strings.IndexByte(text[i+1:], '\''); end >= 0 {
+				i += end + 1
+			} else {
+				i = len(text)
+			}
+		case '"':
+			for i++; i < len(text) && text[i] != '"'; i++ {
+				if text[i] == '\\' {
+					i++
+				}
+			}
+		}
+	}
+
+	if start >= 0 && start < len(text) {
+		words = append(words, text[start:])
+	}
+
+	return words
+}
@@ -0,0 +1,126 @@
+// Copyright (c) 2020 Aritz Olea
+// This file is part of Simplepipe <https://github.com/aritzz/simplepipe>
+//
+// Simplepipe is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Simplepipe is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Simplepipe.  If not, see <https://www.gnu.org/licenses/>.
+
+package load
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitCommand(t *testing.T) {
+	tests := []struct {
+		command string
+		args    []string
+		ok      bool
+	}{
+		{"echo hello  world", []string{"echo", "hello", "world"}, true},
+		{"\techo\t a ", []string{"echo", "a"}, true},
+		{"echo 'a b' \"c d\"", []string{"echo", "a b", "c d"}, true},
+		{"echo a\\ b", []string{"echo", "a b"}, true},
+		{"echo 'it''s'", []string{"echo", "its"}, true},
+		{"echo pre'mid'post", []string{"echo", "premidpost"}, true},
+		{"echo '' \"\"", []string{"echo", "", ""}, true},
+		{"echo $name \"$name\"", []string{"echo", "$name", "$name"}, true},
+		{"echo '$name' \\$name \"\\$name\"", []string{"echo", "$$name", "$$name", "$$name"}, true},
+		{"echo \"a \\\"b\\\" \\\\ \\n\"", []string{"echo", "a \"b\" \\ \\n"}, true},
+		{"echo 'a \\ b'", []string{"echo", "a \\ b"}, true},
+		{"echo (a) \"(b\"", []string{"echo", "(a)", "(b"}, true},
+		{"echo 'open", nil, false},
+		{"echo \"open", nil, false},
+		{"echo a\\", nil, false},
+		{"   ", nil, false},
+	}
+
+	for _, test := range tests {
+		args, err := splitCommand(test.command)
+		if test.ok != (err == nil) {
+			t.Errorf("%q: error %v, want ok %v", test.command, err, test.ok)
+			continue
+		}
+		if test.ok && !reflect.DeepEqual(args, test.args) {
+			t.Errorf("%q: got %q, want %q", test.command, args, test.args)
+		}
+	}
+}
+
+func TestSplitParens(t *testing.T) {
+	tests := []struct {
+		text    string
+		command string
+		rest    string
+		ok      bool
+	}{
+		{"(echo a) timeout 5s", "echo a", "timeout 5s", true},
+		{"(echo (a (b)))", "echo (a (b))", "", true},
+		{"(echo ')' \")\" \\))  retry 2", "echo ')' \")\" \\)", "retry 2", true},
+		{"(echo \"a \\\" )\")", "echo \"a \\\" )\"", "", true},
+		{"(echo a", "", "", false},
+		{"(echo (a)", "", "", false},
+		{"(echo ')", "", "", false},
+	}
+
+	for _, test := range tests {
+		command, rest, err := splitParens(test.text)
+		if test.ok != (err == nil) {
+			t.Errorf("%q: error %v, want ok %v", test.text, err, test.ok)
+			continue
+		}
+		if test.ok && (command != test.command || rest != test.rest) {
+			t.Errorf("%q: got %q, %q, want %q, %q", test.text, command, rest, test.command, test.rest)
+		}
+	}
+}
+
+func TestSplitFields(t *testing.T) {
+	tests := []struct {
+		text   string
+		fields []string
+	}{
+		{"a: $x, b: $y", []string{"a: $x", " b: $y"}},
+		{"a: \"x, y\", b: 'p,q'", []string{"a: \"x, y\"", " b: 'p,q'"}},
+		{"a: x\\, y", []string{"a: x\\, y"}},
+		{"", []string{""}},
+		{"a,", []string{"a", ""}},
+		{"a: 'open, b", []string{"a: 'open, b"}},
+	}
+
+	for _, test := range tests {
+		if fields := splitFields(test.text, ','); !reflect.DeepEqual(fields, test.fields) {
+			t.Errorf("%q: got %q, want %q", test.text, fields, test.fields)
+		}
+	}
+}
+
+func TestSplitRaw(t *testing.T) {
+	tests := []struct {
+		text  string
+		words []string
+	}{
+		{"a  b\tc", []string{"a", "b", "c"}},
+		{"glob \"$dir/*.wav\" 'x y' a\\ b", []string{"glob", "\"$dir/*.wav\"", "'x y'", "a\\ b"}},
+		{"\"a \\\" b\" c", []string{"\"a \\\" b\"", "c"}},
+		{"'open c", []string{"'open c"}},
+		{"a\\", []string{"a\\"}},
+		{"  ", nil},
+	}
+
+	for _, test := range tests {
+		if words := splitRaw(test.text); !reflect.DeepEqual(words, test.words) {
+			t.Errorf("%q: got %q, want %q", test.text, words, test.words)
+		}
+	}
+}
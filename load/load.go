@@ -18,10 +18,9 @@ package load
 
 import (
 	"errors"
-	"fmt"
 	"io/ioutil"
-	"math/rand"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -29,23 +28,59 @@ import (
 )
 
 const RANDOM_LEN = 10
+const RANDOM_MAX_LEN = 4096
+const RANDOM_ALPHABET = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+const DEFAULT_SHELL = "/bin/sh"
+
+// INPUT_TYPES are the types of read declarations
+var INPUT_TYPES = map[string]data.InputType{
+	"string": data.INPUT_STRING,
+	"int":    data.INPUT_INT,
+	"enum":   data.INPUT_ENUM,
+	"file":   data.INPUT_FILE,
+}
 
 // ParseFile Parses file to a pipeline
-// if is not valid, returns an error
+// if is not valid, returns an error. Errors in the pipeline
+// are returned as Diagnostics, with every error found
 func ParseFile(input string) (data.Pipeline, error) {
-	var return_pipe data.Pipeline
-
 	// Read file
 	fileContent, err := loadFile(input)
 	if err != nil {
-		return return_pipe, err
+		return data.Pipeline{}, err
 	}
 
+	return ParseString(input, fileContent)
+}
+
+// ParseString Parses the content of a pipeline file, file is
+// only used in positions. Errors are returned as in ParseFile
+func ParseString(file string, content string) (data.Pipeline, error) {
 	// Get clean pipeline
-	pipeline := cleanRawPipeline(fileContent)
+	pipeline := cleanRawPipeline(file, content)
 
 	// Load pipeline to a struct
-	return loadPipeline(pipeline)
+	return_pipe, err := loadPipeline(pipeline, newDiagnosticList(content))
+	return_pipe.Description = getDescription(content)
+	return return_pipe, err
+}
+
+// Get the description of a pipeline, the comment block at the start of the file
+func getDescription(content string) string {
+	var description []string
+
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 && len(description) == 0 {
+			continue
+		}
+		if !strings.HasPrefix(line, ";") {
+			break
+		}
+		description = append(description, strings.TrimSpace(strings.TrimLeft(line, ";")))
+	}
+
+	return strings.Join(description, "\n")
 }
 
 // Load a file to a string
@@ -58,16 +93,19 @@ func loadFile(input string) (string, error) {
 	return string(filecontent), nil
 }
 
-// Clean raw pipeline
-func cleanRawPipeline(pipe string) []string {
-	fileCleaned := []string{}
+// Clean raw pipeline, lines keep their position in the file
+// and lose their comments
+func cleanRawPipeline(file string, pipe string) []sourceLine {
+	fileCleaned := []sourceLine{}
 	fileLine := strings.Split(pipe, "\n")
 
-	for _, line := range fileLine {
+	for i, line := range fileLine {
 		line_clean := strings.TrimSpace(line)
-		if len(line) > 0 {
+		if len(line_clean) > 0 {
 			if line_clean[0] != ';' {
-				fileCleaned = append(fileCleaned, line_clean)
+				pos := data.Position{File: file, Line: i + 1, Column: strings.Index(line, line_clean) + 1}
+				line_clean, _ = splitComment(line_clean)
+				fileCleaned = append(fileCleaned, sourceLine{Pos: pos, Text: line_clean})
 			}
 		}
 	}
@@ -75,12 +113,15 @@ func cleanRawPipeline(pipe string) []string {
 	return fileCleaned
 }
 
-// Pipeline loader
-func loadPipeline(pipeline []string) (data.Pipeline, error) {
+// Pipeline loader, errors are collected in diags and loading goes on
+// with the next line
+func loadPipeline(pipeline []sourceLine, diags *diagnosticList) (data.Pipeline, error) {
 	var currentStatus int
 	var err error
+	var blocks blockStack
 	pipelineData := data.Pipeline{}
 	pipelineData.Declaration = make(map[string]string)
+	pipelineData.DeclarationPos = make(map[string]data.Position)
 	currentStatus = STATUS_DEFINE
 
 	// for i, line := range pipeline {
@@ -88,87 +129,336 @@ func loadPipeline(pipeline []string) (data.Pipeline, error) {
 		line := pipeline[i]
 		switch currentStatus {
 		case STATUS_DEFINE:
-			if pipelineData.Name, err = getPipelineDefinition(line); err != nil {
-				goto retpipe
-			}
+			pipelineData.Pos = line.Pos
+			pipelineData.Name, err = getPipelineDefinition(line.Text)
 			currentStatus = STATUS_DECLARATION
 		case STATUS_DECLARATION:
 			pipelineData, currentStatus, err = getPipelineDeclaration(line, pipelineData)
-			if err != nil {
-				goto retpipe
-			}
 		case STATUS_PIPELINE:
-			pipelineData, currentStatus, err = getPipelineContent(line, pipelineData)
-			if err != nil {
-				goto retpipe
-			}
+			pipelineData, currentStatus, err = getPipelineContent(line, pipelineData, &blocks)
 			if currentStatus == STATUS_END {
 				i -= 1
 			}
 		case STATUS_END:
 			pipelineData, currentStatus, err = getPipelineEnd(line, pipelineData)
-			if err != nil {
-				goto retpipe
-			}
+		}
+		if err != nil {
+			diags.add(line.Pos, err)
 		}
 	}
 
-retpipe:
-	return pipelineData, err
+	// Blocks must be closed before the pipeline ends
+	if pos, err := blocks.checkClosed(); err != nil {
+		diags.add(pos, err)
+		blocks.closeAll(&pipelineData)
+	}
+
+	// Every used variable must be declared
+	checkVariables(pipelineData, diags)
+
+	return pipelineData, diags.err()
 }
 
 // Get pipeline content
-func getPipelineContent(line string, pipeline data.Pipeline) (data.Pipeline, int, error) {
+func getPipelineContent(source sourceLine, pipeline data.Pipeline, blocks *blockStack) (data.Pipeline, int, error) {
+	line := source.Text
+
+	// Block statements (if, else, endif...)
+	if handled, err := getPipelineBlock(source, &pipeline, blocks); handled {
+		return pipeline, STATUS_PIPELINE, err
+	}
 
-	// Pipeline content ends here
+	// Pipeline content ends here, open blocks are reported at the end
 	if isPipelineEnd(line) {
 		return pipeline, STATUS_END, nil
 	}
 
-	// Assign with execution
-	execassign := regexp.MustCompile(`^(\w+)\s*=\s*\((.+)\)$`)
-	if len(execassign.FindStringSubmatch(line)) == 3 {
-		executionData := data.PipelineExecution{Type: data.TYPE_EXECASSIGN, Command: execassign.FindStringSubmatch(line)[2], Output: execassign.FindStringSubmatch(line)[1]}
-		pipeline.Execution = append(pipeline.Execution, executionData)
-		return pipeline, STATUS_PIPELINE, nil
+	executionData, err := getPipelineStep(line, pipeline)
+	if err != nil {
+		return pipeline, STATUS_PIPELINE, err
+	}
+	executionData.Pos = source.Pos
+	blocks.appendStep(&pipeline, executionData)
+
+	return pipeline, STATUS_PIPELINE, nil
+}
+
+// Get a single step
+func getPipelineStep(line string, pipeline data.Pipeline) (data.PipelineExecution, error) {
+
+	// Assign with execution, to stdout[, stderr[, exit code]]
+	execassign := regexp.MustCompile(`^(\w+)(\s*,\s*(\w+))?(\s*,\s*(\w+))?\s*=\s*(.+)$`)
+	if len(execassign.FindStringSubmatch(line)) == 7 {
+		executionData, ok, err := getExecStep(execassign.FindStringSubmatch(line)[6], pipeline)
+		if ok {
+			executionData.Type = data.TYPE_EXECASSIGN
+			executionData.Output = getOutputName(execassign.FindStringSubmatch(line)[1])
+			executionData.ErrOutput = getOutputName(execassign.FindStringSubmatch(line)[3])
+			executionData.CodeOutput = getOutputName(execassign.FindStringSubmatch(line)[5])
+			return executionData, err
+		}
 	}
 
 	// Simple assign
 	onlyassign := regexp.MustCompile(`^(\w+)\s*=\s*(\w+)$`)
 	if len(onlyassign.FindStringSubmatch(line)) == 3 {
 		executionData := data.PipelineExecution{Type: data.TYPE_ASSIGN, Command: onlyassign.FindStringSubmatch(line)[2], Output: onlyassign.FindStringSubmatch(line)[1]}
-		pipeline.Execution = append(pipeline.Execution, executionData)
-		return pipeline, STATUS_PIPELINE, nil
+		return executionData, nil
+	}
+
+	// Deferred execution
+	deferexec := regexp.MustCompile(`^defer\s+(.+)$`)
+	if len(deferexec.FindStringSubmatch(line)) == 2 {
+		executionData, ok, err := getExecStep(deferexec.FindStringSubmatch(line)[1], pipeline)
+		if ok {
+			executionData.Type = data.TYPE_DEFER
+			return executionData, err
+		}
 	}
 
 	// Only execute
-	onlyexec := regexp.MustCompile(`^\((.+)\)$`)
-	if len(onlyexec.FindStringSubmatch(line)) == 2 {
-		executionData := data.PipelineExecution{Type: data.TYPE_EXEC, Command: onlyexec.FindStringSubmatch(line)[1], Output: ""}
-		pipeline.Execution = append(pipeline.Execution, executionData)
-		return pipeline, STATUS_PIPELINE, nil
+	executionData, ok, err := getExecStep(line, pipeline)
+	if ok {
+		return executionData, err
+	}
+
+	return executionData, errors.New("Invalid line: " + line)
+}
+
+// Get a variable to assign a step output, _ discards it
+func getOutputName(name string) string {
+	if name == "_" {
+		return ""
+	}
+	return name
+}
+
+// Get an execution step like (command) or sh (command), followed by
+// its modifiers. Returns false if line is not an execution step
+func getExecStep(line string, pipeline data.Pipeline) (data.PipelineExecution, bool, error) {
+	executionData := data.PipelineExecution{Type: data.TYPE_EXEC}
+
+	onlyexec := regexp.MustCompile(`^(sh\s*)?\(`)
+	if len(onlyexec.FindStringSubmatch(line)) != 2 {
+		return executionData, false, nil
+	}
+
+	shellmarker := onlyexec.FindStringSubmatch(line)[1]
+	command, rest, err := splitParens(line[len(shellmarker):])
+	if err != nil {
+		return executionData, true, err
+	}
+
+	executionData.Command = command
+	executionData.Args, executionData.Mode, err = getCommandArgs(pipeline, shellmarker, command)
+	if err != nil {
+		return executionData, true, err
+	}
+
+	return executionData, true, getStepModifiers(rest, &executionData)
+}
+
+// Get step modifiers written after the command, like timeout 30s
+func getStepModifiers(text string, step *data.PipelineExecution) error {
+	var err error
+	words := strings.Fields(text)
+
+	for i := 0; i < len(words); i++ {
+		switch words[i] {
+		case "timeout":
+			if i+1 >= len(words) {
+				return errors.New("Missing duration after timeout")
+			}
+			i++
+			if step.Timeout, err = getDuration(words[i]); err != nil {
+				return err
+			}
+		case "retry":
+			retry, next, err := getRetry(words, i+1)
+			if err != nil {
+				return err
+			}
+			step.Retry = &retry
+			i = next - 1
+		case "ignore-errors":
+			step.IgnoreErrors = true
+		default:
+			return newSyntaxError("Unknown step modifier", words[i])
+		}
+	}
+
+	return nil
+}
+
+// Get a retry policy: retry N [backoff fixed D | backoff exponential A..B] [on exit C,D]
+// Returns the index of the first word after the policy
+func getRetry(words []string, i int) (data.PipelineRetry, int, error) {
+	var err error
+	retry := data.PipelineRetry{}
+
+	if i >= len(words) {
+		return retry, i, errors.New("Missing count after retry")
+	}
+	if retry.Retries, err = strconv.Atoi(words[i]); err != nil || retry.Retries < 1 {
+		return retry, i, newSyntaxError("Invalid retry count", words[i])
+	}
+	i++
+
+	for i < len(words) {
+		switch {
+		case words[i] == "backoff" && i+2 < len(words) && words[i+1] == "fixed":
+			retry.Backoff = data.BACKOFF_FIXED
+			if retry.MinDelay, err = getDuration(words[i+2]); err != nil {
+				return retry, i, err
+			}
+			retry.MaxDelay = retry.MinDelay
+		case words[i] == "backoff" && i+2 < len(words) && words[i+1] == "exponential":
+			limits := strings.Split(words[i+2], "..")
+			if len(limits) != 2 {
+				return retry, i, newSyntaxError("Invalid backoff, use exponential 1s..30s", words[i+2])
+			}
+			retry.Backoff = data.BACKOFF_EXPONENTIAL
+			if retry.MinDelay, err = getDuration(limits[0]); err != nil {
+				return retry, i, err
+			}
+			if retry.MaxDelay, err = getDuration(limits[1]); err != nil {
+				return retry, i, err
+			}
+			if retry.MinDelay > retry.MaxDelay {
+				return retry, i, newSyntaxError("Invalid backoff, the first delay is longer than the last", words[i+2])
+			}
+		case words[i] == "on" && i+2 < len(words) && words[i+1] == "exit":
+			for _, code := range strings.Split(words[i+2], ",") {
+				exitcode, err := strconv.Atoi(code)
+				if err != nil {
+					return retry, i, newSyntaxError("Invalid exit code", code)
+				}
+				retry.ExitCodes = append(retry.ExitCodes, exitcode)
+			}
+		default:
+			return retry, i, nil
+		}
+		i += 3
 	}
 
-	return pipeline, STATUS_PIPELINE, errors.New("Invalid line: " + line)
+	return retry, i, nil
 }
 
-func getPipelineEnd(line string, pipeline data.Pipeline) (data.Pipeline, int, error) {
+// Get a positive duration like 30s or 5m
+func getDuration(text string) (time.Duration, error) {
+	duration, err := time.ParseDuration(text)
+	if err != nil || duration <= 0 {
+		return duration, newSyntaxError("Invalid duration", text)
+	}
+	return duration, nil
+}
+
+// Get arguments for a step command
+// shell steps run the command text untouched through the pipeline shell
+func getCommandArgs(pipeline data.Pipeline, shellmarker string, command string) ([]string, data.ExecutionMode, error) {
+	if len(shellmarker) == 0 {
+		args, err := splitCommand(command)
+		return args, data.MODE_DIRECT, err
+	}
+
+	shell := pipeline.Shell
+	if len(shell) == 0 {
+		shell = []string{DEFAULT_SHELL}
+	}
+	args := append(append([]string{}, shell...), "-c", command)
+	return args, data.MODE_SHELL, nil
+}
+
+// Get pipeline end, with the values it returns as end a, b
+// or return { name: value, ... }
+func getPipelineEnd(source sourceLine, pipeline data.Pipeline) (data.Pipeline, int, error) {
+	var err error
+	line := source.Text
+	output := data.PipelineOutput{Pos: source.Pos, Defined: true}
+
 	// Output section
-	outputsec := regexp.MustCompile(`^end\s*([\w]*)$`)
-	if len(outputsec.FindStringSubmatch(line)) == 2 {
-		pipeline.Output = data.PipelineOutput{Defined: true, Value: outputsec.FindStringSubmatch(line)[1]}
-		return pipeline, STATUS_END, nil
+	endsec := regexp.MustCompile(`^end(?:\s+(.+))?$`)
+	returnsec := regexp.MustCompile(`^return\s*\{(.*)\}$`)
+	if pipeline.Output.Defined {
+		return pipeline, STATUS_END, errors.New("Pipeline syntax error: " + line)
+	} else if match := endsec.FindStringSubmatch(line); len(match) == 2 {
+		output.Values, err = getEndValues(match[1])
+	} else if match := returnsec.FindStringSubmatch(line); len(match) == 2 {
+		output.Structured = true
+		output.Values, err = getReturnValues(match[1])
+	} else {
+		return pipeline, STATUS_END, errors.New("Pipeline syntax error: " + line)
+	}
+
+	pipeline.Output = output
+	return pipeline, STATUS_END, err
+}
+
+// Get the values returned by end, a list of variable names
+func getEndValues(text string) ([]data.PipelineOutputValue, error) {
+	var values []data.PipelineOutputValue
+
+	if len(text) == 0 {
+		return values, nil
+	}
+	for _, name := range strings.Split(text, ",") {
+		name = strings.TrimSpace(name)
+		if !regexp.MustCompile(`^\w+$`).MatchString(name) {
+			return values, newSyntaxError("Invalid return variable", name)
+		}
+		if hasOutputValue(values, name) {
+			return values, newSyntaxError("Duplicate return name", name)
+		}
+		values = append(values, data.PipelineOutputValue{Name: name, Value: "$" + name})
 	}
 
-	if len(outputsec.FindStringSubmatch(line)) == 1 {
-		fmt.Println(outputsec.FindStringSubmatch(line))
+	return values, nil
+}
+
+// Get the values returned by return, name: value pairs where
+// value is a single word as in commands
+func getReturnValues(text string) ([]data.PipelineOutputValue, error) {
+	var values []data.PipelineOutputValue
+
+	if len(strings.TrimSpace(text)) == 0 {
+		return values, nil
+	}
+	entry := regexp.MustCompile(`^(\w+)\s*:\s*(.+)$`)
+	for _, field := range splitFields(text, ',') {
+		field = strings.TrimSpace(field)
+		match := entry.FindStringSubmatch(field)
+		if len(match) != 3 {
+			return values, newSyntaxError("Invalid return value, use name: value", field)
+		}
+		words, err := splitCommand(match[2])
+		if err != nil {
+			return values, err
+		}
+		if len(words) != 1 {
+			return values, newSyntaxError("Return value must be a single word, quote it", match[2])
+		}
+		if hasOutputValue(values, match[1]) {
+			return values, newSyntaxError("Duplicate return name", match[1])
+		}
+		values = append(values, data.PipelineOutputValue{Name: match[1], Value: words[0]})
 	}
 
-	return pipeline, STATUS_END, errors.New("Pipeline syntax error: " + line)
+	return values, nil
+}
+
+// Check if a return value name is already used
+func hasOutputValue(values []data.PipelineOutputValue, name string) bool {
+	for _, value := range values {
+		if value.Name == name {
+			return true
+		}
+	}
+	return false
 }
 
 // Get pipeline declaration
-func getPipelineDeclaration(line string, pipeline data.Pipeline) (data.Pipeline, int, error) {
+func getPipelineDeclaration(source sourceLine, pipeline data.Pipeline) (data.Pipeline, int, error) {
+	line := source.Text
 
 	// Declaration end
 	if line == "begin" {
@@ -179,32 +469,151 @@ func getPipelineDeclaration(line string, pipeline data.Pipeline) (data.Pipeline,
 	declaration := regexp.MustCompile(`^use ([\w]+)$`)
 	if len(declaration.FindStringSubmatch(line)) == 2 {
 		pipeline.Declaration[declaration.FindStringSubmatch(line)[1]] = ""
+		pipeline.DeclarationPos[declaration.FindStringSubmatch(line)[1]] = source.Pos
 		return pipeline, STATUS_DECLARATION, nil
 	}
 
-	// Random declaration
-	randomvars := regexp.MustCompile(`^rand ([\w]+)$`)
-	if len(randomvars.FindStringSubmatch(line)) == 2 {
-		pipeline.Declaration[randomvars.FindStringSubmatch(line)[1]] = getRandomString(RANDOM_LEN)
+	// Shell declaration
+	shelldecl := regexp.MustCompile(`^shell (.+)$`)
+	if len(shelldecl.FindStringSubmatch(line)) == 2 {
+		shell, err := splitCommand(shelldecl.FindStringSubmatch(line)[1])
+		if err != nil {
+			return pipeline, STATUS_DECLARATION, err
+		}
+		pipeline.Shell = shell
 		return pipeline, STATUS_DECLARATION, nil
 	}
 
-	// Input section
-	inputsec := regexp.MustCompile(`^read ([\w]+)(\s+"(.*)")?$`)
-	if len(inputsec.FindStringSubmatch(line)) == 2 {
-		pipeline.Input = append(pipeline.Input, data.PipelineInput{Name: inputsec.FindStringSubmatch(line)[1], Value: ""})
-		return pipeline, STATUS_DECLARATION, nil
+	// Pipeline timeout
+	timeoutdecl := regexp.MustCompile(`^timeout (\S+)$`)
+	if len(timeoutdecl.FindStringSubmatch(line)) == 2 {
+		timeout, err := getDuration(timeoutdecl.FindStringSubmatch(line)[1])
+		pipeline.Timeout = timeout
+		return pipeline, STATUS_DECLARATION, err
 	}
+
+	// Random declaration
+	randomvars := regexp.MustCompile(`^rand ([\w]+)(\s+length (\d+))?(\s+alphabet "(.*)")?$`)
+	if len(randomvars.FindStringSubmatch(line)) == 6 {
+		random, err := getRandomDeclaration(randomvars.FindStringSubmatch(line))
+		random.Pos = source.Pos
+		pipeline.Declaration[random.Name] = ""
+		pipeline.DeclarationPos[random.Name] = source.Pos
+		pipeline.Random = append(pipeline.Random, random)
+		return pipeline, STATUS_DECLARATION, err
+	}
+
+	// Temporary files and directories
+	tempvars := regexp.MustCompile(`^(tempfile|tempdir) ([\w]+)(\s+suffix "(.*)")?$`)
+	if len(tempvars.FindStringSubmatch(line)) == 5 {
+		temp, err := getTempDeclaration(tempvars.FindStringSubmatch(line))
+		temp.Pos = source.Pos
+		pipeline.Declaration[temp.Name] = ""
+		pipeline.DeclarationPos[temp.Name] = source.Pos
+		pipeline.Temp = append(pipeline.Temp, temp)
+		return pipeline, STATUS_DECLARATION, err
+	}
+
+	// Input section
+	inputsec := regexp.MustCompile(`^read ([\w]+)(\s+(.*))?$`)
 	if len(inputsec.FindStringSubmatch(line)) == 4 {
-		// pipeline.Input[inputsec.FindStringSubmatch(line)[1]] = inputsec.FindStringSubmatch(line)[3]
-		pipeline.Input = append(pipeline.Input, data.PipelineInput{Name: inputsec.FindStringSubmatch(line)[1], Value: inputsec.FindStringSubmatch(line)[3]})
-		return pipeline, STATUS_DECLARATION, nil
+		input, err := getInputDeclaration(inputsec.FindStringSubmatch(line)[1], inputsec.FindStringSubmatch(line)[3])
+		input.Pos = source.Pos
+		for _, other := range pipeline.Input {
+			if input.Stdin && other.Stdin && err == nil {
+				err = errors.New("Only one input can be read from stdin, " + other.Name + " already is")
+			}
+		}
+		pipeline.Input = append(pipeline.Input, input)
+		return pipeline, STATUS_DECLARATION, err
 	}
 
 	// Invalid
 	return pipeline, STATUS_DECLARATION, errors.New("Invalid line in declaration: " + line)
 }
 
+// Get an input declaration: read name ["description"] [type] [from source] [default value]
+// where type is string, int [range a..b], enum a,b,c or file [exists]
+// and source is env VAR or stdin
+func getInputDeclaration(name string, text string) (data.PipelineInput, error) {
+	input := data.PipelineInput{Name: name}
+
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "\"") {
+		end := strings.IndexByte(text[1:], '"')
+		if end < 0 {
+			return input, newSyntaxError("Unterminated input description", text)
+		}
+		input.Description = text[1 : end+1]
+		text = strings.TrimSpace(text[end+2:])
+	}
+	if len(text) == 0 {
+		return input, nil
+	}
+
+	words, err := splitCommand(text)
+	if err != nil {
+		return input, err
+	}
+	typed := false
+	for i := 0; i < len(words); i++ {
+		word := words[i]
+		switch {
+		case !typed && isInputType(word):
+			typed = true
+			input.Type = INPUT_TYPES[word]
+			if word != "enum" {
+				continue
+			}
+			if i+1 >= len(words) {
+				return input, errors.New("Missing values after enum")
+			}
+			i++
+			input.Enum = strings.Split(words[i], ",")
+		case word == "range" && input.Type == data.INPUT_INT && !input.HasRange && i+1 < len(words):
+			i++
+			limits := strings.Split(words[i], "..")
+			min, errmin := strconv.Atoi(limits[0])
+			max, errmax := strconv.Atoi(limits[len(limits)-1])
+			if len(limits) != 2 || errmin != nil || errmax != nil || min > max {
+				return input, newSyntaxError("Invalid range, use range 64..320", words[i])
+			}
+			input.HasRange, input.Min, input.Max = true, min, max
+		case word == "exists" && input.Type == data.INPUT_FILE && !input.Exists:
+			input.Exists = true
+		case word == "from" && i+1 < len(words) && words[i+1] == "stdin" && !input.Stdin && len(input.Env) == 0:
+			i++
+			input.Stdin = true
+		case word == "from" && i+2 < len(words) && words[i+1] == "env" && !input.Stdin && len(input.Env) == 0:
+			i += 2
+			input.Env = words[i]
+		case word == "secret" && !input.Secret:
+			input.Secret = true
+		case word == "default" && !input.HasDefault && i+1 < len(words):
+			i++
+			input.HasDefault = true
+			input.Default = strings.ReplaceAll(words[i], "$$", "$")
+		default:
+			return input, newSyntaxError("Invalid input declaration", word)
+		}
+	}
+
+	// A file default may be created by the time the pipeline runs
+	if input.HasDefault && !input.Exists {
+		if err := input.CheckValue(input.Default); err != nil {
+			return input, err
+		}
+	}
+
+	return input, nil
+}
+
+// Check if a word is an input type
+func isInputType(word string) bool {
+	_, ok := INPUT_TYPES[word]
+	return ok
+}
+
 // Get pipeline definition
 func getPipelineDefinition(line string) (string, error) {
 	var retstring string
@@ -222,18 +631,42 @@ func getPipelineDefinition(line string) (string, error) {
 
 // Detect ending
 func isPipelineEnd(line string) bool {
-	outputsec := regexp.MustCompile(`^end\s*([\w]*)$`)
+	outputsec := regexp.MustCompile(`^(end(\s+[\w\s,]*)?|return\s*\{.*)$`)
 	return outputsec.MatchString(line)
 }
 
-// Get random string
-func getRandomString(length int) string {
-	var b strings.Builder
-	rand.Seed(time.Now().UnixNano())
-	chars := []rune("ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789")
+// Get a rand declaration, with its optional length and alphabet.
+// The value is generated on every run
+func getRandomDeclaration(match []string) (data.PipelineRandom, error) {
+	random := data.PipelineRandom{Name: match[1], Length: RANDOM_LEN, Alphabet: RANDOM_ALPHABET}
+
+	if len(match[3]) > 0 {
+		value, err := strconv.Atoi(match[3])
+		if err != nil || value <= 0 || value > RANDOM_MAX_LEN {
+			return random, newSyntaxError("Invalid random length", match[3])
+		}
+		random.Length = value
+	}
+	if len(match[4]) > 0 {
+		if len(match[5]) == 0 {
+			return random, errors.New("Empty random alphabet: " + match[0])
+		}
+		random.Alphabet = match[5]
+	}
+
+	return random, nil
+}
+
+// Get a tempfile or tempdir declaration
+func getTempDeclaration(match []string) (data.PipelineTemp, error) {
+	temp := data.PipelineTemp{Name: match[2], Dir: match[1] == "tempdir", Suffix: match[4]}
 
-	for i := 0; i < length; i++ {
-		b.WriteRune(chars[rand.Intn(len(chars))])
+	if temp.Dir && len(match[3]) > 0 {
+		return temp, errors.New("A temporary directory can't have a suffix: " + match[0])
 	}
-	return b.String()
+	if strings.ContainsAny(temp.Suffix, "/\\") {
+		return temp, newSyntaxError("Invalid temporary file suffix", temp.Suffix)
+	}
+
+	return temp, nil
 }
@@ -0,0 +1,89 @@
+// Copyright (c) 2020 Aritz Olea
+// This file is part of Simplepipe <https://github.com/aritzz/simplepipe>
+//
+// Simplepipe is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Simplepipe is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Simplepipe.  If not, see <https://www.gnu.org/licenses/>.
+
+package load
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRetryBackoff(t *testing.T) {
+	tests := []struct {
+		modifiers string
+		min       time.Duration
+		max       time.Duration
+		err       string
+	}{
+		{"retry 3", 0, 0, ""},
+		{"retry 3 backoff fixed 2s", 2 * time.Second, 2 * time.Second, ""},
+		{"retry 3 backoff exponential 1s..30s", time.Second, 30 * time.Second, ""},
+		{"retry 3 backoff exponential 5s..5s", 5 * time.Second, 5 * time.Second, ""},
+		{"retry 3 backoff exponential 30s..1s", 0, 0, "Invalid backoff, the first delay is longer than the last: 30s..1s"},
+		{"retry 3 backoff exponential 1s", 0, 0, "Invalid backoff"},
+		{"retry 0", 0, 0, "Invalid retry count"},
+	}
+
+	for _, test := range tests {
+		pipeline, err := ParseString("test.pipe", "pipeline retry\nbegin\n  (true) "+test.modifiers+"\nend\n")
+		if len(test.err) > 0 {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%s: got error %v, want %q", test.modifiers, err, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.modifiers, err)
+			continue
+		}
+		retry := pipeline.Execution[0].Retry
+		if retry.MinDelay != test.min || retry.MaxDelay != test.max {
+			t.Errorf("%s: got %v..%v, want %v..%v", test.modifiers, retry.MinDelay, retry.MaxDelay, test.min, test.max)
+		}
+	}
+}
+
+func TestPipelineEnd(t *testing.T) {
+	pipeline, err := ParseString("test.pipe", `pipeline end
+  use end
+  use out
+begin
+  end = (date)
+  out = end
+end out, end
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pipeline.Execution) != 2 || pipeline.Execution[0].Output != "end" {
+		t.Errorf("got steps %+v, want 2 with the first one assigning end", pipeline.Execution)
+	}
+	if values := pipeline.Output.Values; len(values) != 2 || values[0].Name != "out" || values[1].Name != "end" {
+		t.Errorf("got returned values %+v, want out and end", values)
+	}
+
+	for _, line := range []string{"end", "end out", "end out, end", "return { a: $out }"} {
+		if !isPipelineEnd(line) {
+			t.Errorf("%q is not the end of the pipeline", line)
+		}
+	}
+	for _, line := range []string{"end = (date)", "end, code = (date)", "end = out", "endif"} {
+		if isPipelineEnd(line) {
+			t.Errorf("%q is the end of the pipeline", line)
+		}
+	}
+}
@@ -0,0 +1,267 @@
+// Copyright (c) 2020 Aritz Olea
+// This file is part of Simplepipe <https://github.com/aritzz/simplepipe>
+//
+// Simplepipe is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Simplepipe is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Simplepipe.  If not, see <https://www.gnu.org/licenses/>.
+
+package load
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+)
+
+// INDENT is the indentation of each level in canonical source
+const INDENT = "  "
+
+// Format Get the canonical source of a pipeline file
+func Format(file string, content []byte) ([]byte, error) {
+	tree, err := ParseSyntax(file, string(content))
+	if err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	tree.Print(&out)
+	return out.Bytes(), nil
+}
+
+// Print Write the syntax tree as canonical source: two spaces of
+// indentation per level, one statement per line with single spaces,
+// comments as "; text" and no repeated blank lines
+func (tree *SyntaxTree) Print(out *bytes.Buffer) {
+	level := 0
+	printNodes(out, tree.Nodes, func(node *Node) int {
+		switch node.Kind {
+		case NODE_DEFINITION:
+			level = 1
+			return 0
+		case NODE_BEGIN, NODE_END:
+			if node.Kind == NODE_END {
+				level = 0
+			}
+			return 0
+		}
+		return level
+	})
+}
+
+// printNodes Print a list of nodes, levelOf gives the level of each one
+func printNodes(out *bytes.Buffer, nodes []*Node, levelOf func(*Node) int) {
+	for i, node := range nodes {
+		if node.Kind == NODE_BLANK && !keepBlank(nodes, i) {
+			continue
+		}
+		level := levelOf(node)
+
+		if node.Kind == NODE_BLANK {
+			out.WriteString("\n")
+			continue
+		}
+		out.WriteString(strings.Repeat(INDENT, level) + canonicalNode(node))
+		if len(node.Comment) > 0 {
+			out.WriteString(" " + canonicalComment(node.Comment))
+		}
+		out.WriteString("\n")
+
+		if node.Kind == NODE_BLOCK {
+			printNodes(out, node.Children, func(child *Node) int {
+				if child.Kind == NODE_CLAUSE || child.Kind == NODE_BLOCK_END {
+					return level
+				}
+				return level + 1
+			})
+		}
+	}
+}
+
+// keepBlank Check if a blank line is kept: only one in a row, and
+// never at the start or end of a block or the file
+func keepBlank(nodes []*Node, i int) bool {
+	if i == 0 || nodes[i-1].Kind == NODE_BLANK {
+		return false
+	}
+	for _, next := range nodes[i+1:] {
+		if next.Kind != NODE_BLANK {
+			return next.Kind != NODE_CLAUSE && next.Kind != NODE_BLOCK_END
+		}
+	}
+	return false
+}
+
+// canonicalNode Get the canonical text of a node
+func canonicalNode(node *Node) string {
+	switch node.Kind {
+	case NODE_COMMENT:
+		return canonicalComment(node.Text)
+	case NODE_DEFINITION:
+		return "pipeline " + strings.TrimSpace(strings.TrimPrefix(node.Text, "pipeline"))
+	case NODE_DECLARATION:
+		return canonicalDeclaration(node.Text)
+	case NODE_STEP:
+		return canonicalStep(node.Text)
+	case NODE_BLOCK, NODE_CLAUSE:
+		return canonicalBlock(node.Text)
+	case NODE_END:
+		return canonicalEnd(node.Text)
+	}
+	return node.Text
+}
+
+// canonicalComment Get the canonical text of a comment
+func canonicalComment(comment string) string {
+	text := strings.TrimSpace(strings.TrimLeft(comment, ";"))
+	if len(text) == 0 {
+		return ";"
+	}
+	return "; " + text
+}
+
+// canonicalEnd Get the canonical text of end or return
+func canonicalEnd(line string) string {
+	if match := regexp.MustCompile(`^return\s*\{(.*)\}$`).FindStringSubmatch(line); len(match) == 2 {
+		var fields []string
+		for _, field := range splitFields(match[1], ',') {
+			if parts := strings.SplitN(field, ":", 2); len(parts) == 2 {
+				fields = append(fields, strings.TrimSpace(parts[0])+": "+strings.Join(splitRaw(parts[1]), " "))
+			}
+		}
+		if len(fields) == 0 {
+			return "return {}"
+		}
+		return "return { " + strings.Join(fields, ", ") + " }"
+	}
+
+	var names []string
+	for _, name := range strings.Split(strings.TrimPrefix(line, "end"), ",") {
+		if name = strings.TrimSpace(name); len(name) > 0 {
+			names = append(names, name)
+		}
+	}
+	return strings.TrimSpace("end " + strings.Join(names, ", "))
+}
+
+// canonicalDeclaration Get the canonical text of a declaration
+func canonicalDeclaration(line string) string {
+	if match := regexp.MustCompile(`^shell (.+)$`).FindStringSubmatch(line); len(match) == 2 {
+		return "shell " + strings.Join(splitRaw(match[1]), " ")
+	}
+	// Descriptions are kept as written
+	if match := regexp.MustCompile(`^read\s+(\w+)\s+(.+)$`).FindStringSubmatch(line); len(match) == 3 {
+		words := []string{"read", match[1]}
+		rest := strings.TrimSpace(match[2])
+		if end := strings.IndexByte(rest[1:], '"'); rest[0] == '"' && end >= 0 {
+			words = append(words, rest[:end+2])
+			rest = rest[end+2:]
+		}
+		return strings.Join(append(words, splitRaw(rest)...), " ")
+	}
+	if match := regexp.MustCompile(`^(rand|tempfile|tempdir)\s+(\w+)\s+(.+)$`).FindStringSubmatch(line); len(match) == 4 {
+		return match[1] + " " + match[2] + " " + strings.Join(splitRaw(match[3]), " ")
+	}
+	return strings.Join(strings.Fields(line), " ")
+}
+
+// canonicalStep Get the canonical text of a step
+func canonicalStep(line string) string {
+	execassign := regexp.MustCompile(`^(\w+)(\s*,\s*(\w+))?(\s*,\s*(\w+))?\s*=\s*(.+)$`)
+	if match := execassign.FindStringSubmatch(line); len(match) == 7 {
+		if exec, ok := canonicalExec(match[6]); ok {
+			outputs := match[1]
+			for _, output := range []string{match[3], match[5]} {
+				if len(output) > 0 {
+					outputs += ", " + output
+				}
+			}
+			return outputs + " = " + exec
+		}
+	}
+
+	if match := regexp.MustCompile(`^(\w+)\s*=\s*(\w+)$`).FindStringSubmatch(line); len(match) == 3 {
+		return match[1] + " = " + match[2]
+	}
+
+	if match := regexp.MustCompile(`^defer\s+(.+)$`).FindStringSubmatch(line); len(match) == 2 {
+		if exec, ok := canonicalExec(match[1]); ok {
+			return "defer " + exec
+		}
+	}
+
+	if exec, ok := canonicalExec(line); ok {
+		return exec
+	}
+	return line
+}
+
+// canonicalExec Get the canonical text of an execution and its modifiers,
+// the command itself is kept as written. Returns false if text is not
+// an execution
+func canonicalExec(text string) (string, bool) {
+	match := regexp.MustCompile(`^(sh\s*)?\(`).FindStringSubmatch(text)
+	if len(match) != 2 {
+		return text, false
+	}
+
+	command, rest, err := splitParens(text[len(match[1]):])
+	if err != nil {
+		return text, false
+	}
+
+	exec := "(" + command + ")"
+	if len(match[1]) > 0 {
+		exec = "sh " + exec
+	}
+	if modifiers := strings.Fields(rest); len(modifiers) > 0 {
+		exec += " " + strings.Join(modifiers, " ")
+	}
+	return exec, true
+}
+
+// canonicalBlock Get the canonical text of a block statement
+func canonicalBlock(line string) string {
+	if match := regexp.MustCompile(`^else\s+if\s+(.+)$`).FindStringSubmatch(line); len(match) == 2 {
+		return "else if " + canonicalCondition(match[1])
+	}
+	if match := regexp.MustCompile(`^if\s+(.+)$`).FindStringSubmatch(line); len(match) == 2 {
+		return "if " + canonicalCondition(match[1])
+	}
+	if match := regexp.MustCompile(`^foreach\s+(\w+)\s+in\s+(.+)$`).FindStringSubmatch(line); len(match) == 3 {
+		if exec, ok := canonicalExec(match[2]); ok {
+			return "foreach " + match[1] + " in " + exec
+		}
+		return "foreach " + match[1] + " in " + strings.Join(splitRaw(match[2]), " ")
+	}
+	if match := regexp.MustCompile(`^catch\s+(\w+)(\s*,\s*(\w+))?$`).FindStringSubmatch(line); len(match) == 4 {
+		if len(match[3]) > 0 {
+			return "catch " + match[1] + ", " + match[3]
+		}
+		return "catch " + match[1]
+	}
+	return strings.Join(strings.Fields(line), " ")
+}
+
+// canonicalCondition Get the canonical text of an if condition
+func canonicalCondition(text string) string {
+	text = strings.TrimSpace(text)
+	prefix := ""
+	if strings.HasPrefix(text, "!") {
+		prefix = "! "
+		text = strings.TrimSpace(text[1:])
+	}
+
+	if exec, ok := canonicalExec(text); ok {
+		return prefix + exec
+	}
+	return prefix + strings.Join(splitRaw(text), " ")
+}
@@ -0,0 +1,231 @@
+// Copyright (c) 2020 Aritz Olea
+// This file is part of Simplepipe <https://github.com/aritzz/simplepipe>
+//
+// Simplepipe is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Simplepipe is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Simplepipe.  If not, see <https://www.gnu.org/licenses/>.
+
+package load
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aritzz/simplepipe/data"
+)
+
+// formatTests are pipelines written in non canonical ways
+var formatTests = []struct {
+	name   string
+	source string
+}{
+	{"comments and blank lines", `;Say hello
+;   to someone
+
+
+pipeline Hello
+    ; inputs
+  read name "your name"
+
+
+  use greeting
+begin
+;greet
+    greeting =  (echo Hello $name)
+
+
+
+end   greeting
+`},
+	{"declarations", `pipeline Declarations
+  read src "source file" file exists
+  read bitrate "bitrate in kbps" int range 64..320 default 128
+  read fmt enum mp3,ogg default mp3
+  read token "api token" from env API_TOKEN secret
+  read body from stdin
+  rand id  length 16   alphabet "0123456789abcdef"
+  rand other
+  tempfile tmp suffix ".wav"
+  tempdir work
+  shell   /bin/bash
+  timeout 10m
+  use out
+begin
+  out = (echo $src $bitrate $fmt $token $body $id $other $tmp $work)
+return {  file: $out,   format:"$fmt" }
+`},
+	{"blocks", `pipeline Blocks
+  read fmt default mp3
+  use item
+  use out
+begin
+  if $fmt == "mp3"
+    (echo mp3)
+  else   if ! (test -f $fmt)
+      (echo missing)
+  else if empty $fmt
+    (echo empty)
+  else
+    (echo other)
+  endif
+  foreach f in glob "*.wav"
+    (echo $f)
+  endfor
+  foreach f in (ls)
+    foreach g   in a "b c" $fmt
+      (echo $f $g)
+    endfor
+  endfor
+  parallel   max 2   waitall
+    (echo one) timeout 5s
+    out = sh (echo two)
+  endparallel
+  try
+    (false)
+  catch msg,code
+    (echo $msg $code)
+  finally
+    defer   (echo done)
+  endtry
+  try
+    (true)
+  catch
+  endtry
+end out
+`},
+	{"modifiers and quoting", `pipeline Modifiers
+  use out
+  use err
+  use code
+begin
+  (ffmpeg -i "a b.wav"   'c $d.mp3' e\ f)   timeout 30s
+  (curl $$HOME) retry 3 backoff exponential 1s..30s on exit 6,7 timeout 1m
+  (curl x)   retry 2 backoff fixed 5s ignore-errors
+  out, err, code = sh (cat "$out" | gzip > "${out}.gz")
+  _, err = (ls "$out")
+  code = out
+end
+`},
+	{"trailing comments", `pipeline Trailing ;the name
+  read fmt "a;b" default "mp3;ogg"   ; input
+  use out;;the output
+begin
+  out = sh (echo "$fmt;" ; echo done)   timeout 5s;why
+  if $fmt == "mp3;ogg" ; both
+    (echo $fmt) ;
+  endif ; done
+end out ; returned
+`},
+}
+
+// TestFormatRoundTrip Check that a formatted pipeline loads as the original one
+func TestFormatRoundTrip(t *testing.T) {
+	for _, test := range formatTests {
+		t.Run(test.name, func(t *testing.T) {
+			formatted, err := Format("test.pipe", []byte(test.source))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			original := parseSource(t, test.source)
+			reloaded := parseSource(t, string(formatted))
+			if !reflect.DeepEqual(original, reloaded) {
+				t.Errorf("formatted pipeline differs\noriginal: %+v\nformatted: %+v\nsource:\n%s", original, reloaded, formatted)
+			}
+		})
+	}
+}
+
+// TestFormatIdempotent Check that formatting canonical source doesn't change it
+func TestFormatIdempotent(t *testing.T) {
+	for _, test := range formatTests {
+		t.Run(test.name, func(t *testing.T) {
+			formatted, err := Format("test.pipe", []byte(test.source))
+			if err != nil {
+				t.Fatal(err)
+			}
+			again, err := Format("test.pipe", formatted)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(again) != string(formatted) {
+				t.Errorf("formatting is not idempotent\nfirst:\n%s\nsecond:\n%s", formatted, again)
+			}
+		})
+	}
+}
+
+// TestFormatTrailingComments Check that comments after the code of a
+// line are kept, and that ; inside quotes or commands is not one
+func TestFormatTrailingComments(t *testing.T) {
+	formatted, err := Format("test.pipe", []byte(formatTests[len(formatTests)-1].source))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `pipeline Trailing ; the name
+  read fmt "a;b" default "mp3;ogg" ; input
+  use out ; the output
+begin
+  out = sh (echo "$fmt;" ; echo done) timeout 5s ; why
+  if $fmt == "mp3;ogg" ; both
+    (echo $fmt) ;
+  endif ; done
+end out ; returned
+`
+	if string(formatted) != want {
+		t.Errorf("got\n%s\nwant\n%s", formatted, want)
+	}
+
+	pipeline := parseSource(t, string(formatted))
+	if execstep := pipeline.Execution[0]; execstep.Command != `echo "$fmt;" ; echo done` || execstep.Timeout != 5*time.Second {
+		t.Errorf("got step %q with timeout %v", execstep.Command, execstep.Timeout)
+	}
+	if input := pipeline.Input[0]; input.Description != "a;b" || input.Default != "mp3;ogg" {
+		t.Errorf("got input %q with default %q", input.Description, input.Default)
+	}
+}
+
+// parseSource Load a pipeline from source, without positions
+func parseSource(t *testing.T, source string) data.Pipeline {
+	pipeline, err := ParseString("test.pipe", source)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pipeline.DeclarationPos = nil
+	clearPositions(reflect.ValueOf(&pipeline).Elem())
+	return pipeline
+}
+
+// clearPositions Zero every data.Position inside a value
+func clearPositions(value reflect.Value) {
+	switch value.Kind() {
+	case reflect.Struct:
+		if value.Type() == reflect.TypeOf(data.Position{}) {
+			value.Set(reflect.Zero(value.Type()))
+			return
+		}
+		for i := 0; i < value.NumField(); i++ {
+			clearPositions(value.Field(i))
+		}
+	case reflect.Ptr:
+		if !value.IsNil() {
+			clearPositions(value.Elem())
+		}
+	case reflect.Slice:
+		for i := 0; i < value.Len(); i++ {
+			clearPositions(value.Index(i))
+		}
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright (c) 2020 Aritz Olea
+// This file is part of Simplepipe <https://github.com/aritzz/simplepipe>
+//
+// Simplepipe is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Simplepipe is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Simplepipe.  If not, see <https://www.gnu.org/licenses/>.
+
+package load
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/aritzz/simplepipe/data"
+)
+
+// NodeKind is the kind of a syntax tree node
+type NodeKind int
+
+const (
+	NODE_COMMENT NodeKind = iota
+	NODE_BLANK
+	NODE_DEFINITION
+	NODE_DECLARATION
+	NODE_BEGIN
+	NODE_STEP
+	NODE_BLOCK
+	NODE_CLAUSE
+	NODE_BLOCK_END
+	NODE_END
+)
+
+// Node is a line of a pipeline file. Blocks (if, foreach, parallel, try)
+// hold every line up to their closing one as children, clauses like else
+// or catch and the closing line included. Comment is the comment written
+// after the code of the line, if any
+type Node struct {
+	Pos      data.Position
+	Kind     NodeKind
+	Text     string
+	Comment  string
+	Children []*Node
+}
+
+// SyntaxTree is a pipeline file as it was written, with its
+// comments and blank lines
+type SyntaxTree struct {
+	Nodes []*Node
+}
+
+// ParseSyntax Get the syntax tree of the content of a pipeline file, file
+// is only used for positions. A pipeline that doesn't load returns its
+// Diagnostics as error
+func ParseSyntax(file string, content string) (*SyntaxTree, error) {
+	if _, err := loadPipeline(cleanRawPipeline(file, content), newDiagnosticList(content)); err != nil {
+		return nil, err
+	}
+
+	tree := &SyntaxTree{}
+	var blocks []*Node
+	currentStatus := STATUS_DEFINE
+
+	for i, line := range strings.Split(content, "\n") {
+		text := strings.TrimSpace(line)
+		node := &Node{Pos: data.Position{File: file, Line: i + 1, Column: strings.Index(line, text) + 1}, Text: text}
+
+		if len(text) > 0 && text[0] != ';' {
+			node.Text, node.Comment = splitComment(text)
+			text = node.Text
+		}
+
+		switch {
+		case len(text) == 0:
+			node.Kind = NODE_BLANK
+		case text[0] == ';':
+			node.Kind = NODE_COMMENT
+		case currentStatus == STATUS_DEFINE:
+			node.Kind = NODE_DEFINITION
+			currentStatus = STATUS_DECLARATION
+		case currentStatus == STATUS_DECLARATION && text == "begin":
+			node.Kind = NODE_BEGIN
+			currentStatus = STATUS_PIPELINE
+		case currentStatus == STATUS_DECLARATION:
+			node.Kind = NODE_DECLARATION
+		default:
+			node.Kind = getNodeKind(text)
+			if node.Kind == NODE_END {
+				currentStatus = STATUS_END
+			}
+		}
+
+		// Closing lines belong to the block they close
+		if node.Kind == NODE_BLOCK_END {
+			blocks[len(blocks)-1].Children = append(blocks[len(blocks)-1].Children, node)
+			blocks = blocks[:len(blocks)-1]
+			continue
+		}
+
+		if len(blocks) > 0 {
+			blocks[len(blocks)-1].Children = append(blocks[len(blocks)-1].Children, node)
+		} else {
+			tree.Nodes = append(tree.Nodes, node)
+		}
+		if node.Kind == NODE_BLOCK {
+			blocks = append(blocks, node)
+		}
+	}
+
+	// The last line break is not a blank line
+	if last := len(tree.Nodes) - 1; last >= 0 && tree.Nodes[last].Kind == NODE_BLANK && strings.HasSuffix(content, "\n") {
+		tree.Nodes = tree.Nodes[:last]
+	}
+
+	return tree, nil
+}
+
+// getNodeKind Get the kind of a line of the begin section
+func getNodeKind(text string) NodeKind {
+	switch {
+	case regexp.MustCompile(`^(if\s+.+|foreach\s+\w+\s+in\s+.+|parallel(\s+max\s+\d+)?(\s+(failfast|waitall))?|try)$`).MatchString(text):
+		return NODE_BLOCK
+	case regexp.MustCompile(`^(else\s+if\s+.+|else|catch(\s+\w+(\s*,\s*\w+)?)?|finally)$`).MatchString(text):
+		return NODE_CLAUSE
+	case regexp.MustCompile(`^(endif|endfor|endparallel|endtry)$`).MatchString(text):
+		return NODE_BLOCK_END
+	case isPipelineEnd(text):
+		return NODE_END
+	}
+	return NODE_STEP
+}
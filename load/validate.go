@@ -0,0 +1,204 @@
+// Copyright (c) 2020 Aritz Olea
+// This file is part of Simplepipe <https://github.com/aritzz/simplepipe>
+//
+// Simplepipe is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Simplepipe is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Simplepipe.  If not, see <https://www.gnu.org/licenses/>.
+
+package load
+
+import (
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/aritzz/simplepipe/data"
+)
+
+// ValidateFile Load a pipeline file and check it for mistakes that
+// loading allows, like unused variables. Returns every diagnostic found,
+// the error is only set if the file can't be read
+func ValidateFile(input string) (Diagnostics, error) {
+	fileContent, err := loadFile(input)
+	if err != nil {
+		return nil, err
+	}
+
+	return ValidateString(input, fileContent), nil
+}
+
+// ValidateString Check the content of a pipeline file as ValidateFile
+// does, file is only used in positions
+func ValidateString(file string, content string) Diagnostics {
+	lines := cleanRawPipeline(file, content)
+	diags := newDiagnosticList(content)
+	pipeline, _ := loadPipeline(lines, diags)
+
+	checkDuplicates(lines, diags)
+	v := validator{pipeline: pipeline, diags: diags, used: make(map[string]bool), paths: make(map[string]bool)}
+	v.validate()
+
+	return diags.sorted()
+}
+
+// checkDuplicates Check that no variable is declared twice
+func checkDuplicates(lines []sourceLine, diags *diagnosticList) {
+	declaration := regexp.MustCompile(`^(use|rand|read|tempfile|tempdir)\s+(\w+)`)
+	declared := make(map[string]bool)
+
+	for i := 1; i < len(lines) && lines[i].Text != "begin"; i++ {
+		match := declaration.FindStringSubmatch(lines[i].Text)
+		if len(match) != 3 {
+			continue
+		}
+		if declared[match[2]] {
+			diags.add(subPos(lines[i].Pos, lines[i].Text, lines[i].Text[len(match[1]):]), newSyntaxError("Variable is declared twice", match[2]))
+		}
+		declared[match[2]] = true
+	}
+}
+
+// validator Semantic checks of a loaded pipeline
+type validator struct {
+	pipeline data.Pipeline
+	diags    *diagnosticList
+	used     map[string]bool
+	paths    map[string]bool
+}
+
+// validate Check the whole pipeline
+func (v *validator) validate() {
+	v.block(v.pipeline.Execution)
+
+	// Returned variables, the loader checks they are declared
+	for _, output := range v.pipeline.Output.Values {
+		v.templates([]string{output.Value})
+	}
+
+	// Unused variables
+	for _, input := range v.pipeline.Input {
+		if !v.used[input.Name] {
+			v.diags.warn(input.Pos, newSyntaxError("Input is never used", input.Name))
+		}
+	}
+	for name := range v.pipeline.Declaration {
+		if !v.used[name] {
+			v.diags.warn(v.pipeline.DeclarationPos[name], newSyntaxError("Variable is never used", name))
+		}
+	}
+}
+
+// block Check every step in a block
+func (v *validator) block(block data.PipelineBlock) {
+	for _, execItem := range block {
+		v.step(execItem)
+	}
+}
+
+// step Check a single step and the blocks it holds
+func (v *validator) step(execstep data.PipelineExecution) {
+	if execstep.Type != data.TYPE_FOREACH {
+		for _, output := range []string{execstep.Output, execstep.ErrOutput, execstep.CodeOutput} {
+			if len(output) > 0 && v.isInput(output) {
+				v.diags.add(execstep.Pos, newSyntaxError("Cannot assign to input", output))
+			}
+		}
+	}
+
+	switch execstep.Type {
+	case data.TYPE_ASSIGN:
+		v.used[execstep.Command] = true
+	case data.TYPE_EXEC, data.TYPE_EXECASSIGN, data.TYPE_DEFER:
+		v.command(execstep)
+	case data.TYPE_IF:
+		for _, branch := range execstep.Branches {
+			if branch.Condition != nil && branch.Condition.Type == data.COND_EXEC {
+				v.command(branch.Condition.Exec)
+			} else if branch.Condition != nil {
+				v.templates([]string{branch.Condition.Left, branch.Condition.Right})
+			}
+			v.block(branch.Body)
+		}
+	case data.TYPE_FOREACH:
+		if execstep.Loop.Type == data.LOOP_EXEC {
+			v.command(execstep.Loop.Exec)
+		} else {
+			v.templates(execstep.Loop.Items)
+		}
+		v.block(execstep.Body)
+	case data.TYPE_PARALLEL:
+		v.block(execstep.Body)
+	case data.TYPE_TRY:
+		v.block(execstep.Body)
+		v.block(execstep.Try.Catch)
+		v.block(execstep.Try.Finally)
+	}
+}
+
+// command Check a step command, its executable must be found
+func (v *validator) command(execstep data.PipelineExecution) {
+	if len(execstep.Args) == 0 {
+		return
+	}
+
+	// Shell steps read variables from the environment
+	if execstep.Mode == data.MODE_SHELL {
+		for _, match := range regexp.MustCompile(`\$\{?(\w+)`).FindAllStringSubmatch(execstep.Command, -1) {
+			v.used[match[1]] = true
+		}
+	} else {
+		v.templates(execstep.Args)
+	}
+
+	// Shell steps run the pipeline shell. Only plain names and absolute
+	// paths are looked up, relative paths depend on the working directory
+	executable := execstep.Args[0]
+	if strings.Contains(executable, "$") || (strings.ContainsAny(executable, "/\\") && !filepath.IsAbs(executable)) {
+		return
+	}
+	found, ok := v.paths[executable]
+	if !ok {
+		_, err := exec.LookPath(executable)
+		found = err == nil
+		v.paths[executable] = found
+	}
+	switch {
+	case found:
+	case execstep.Mode == data.MODE_SHELL:
+		v.diags.warn(execstep.Pos, newSyntaxError("Shell not found", executable))
+	case filepath.IsAbs(executable):
+		v.diags.warn(execstep.Pos, newSyntaxError("Command not found", executable))
+	default:
+		v.diags.warn(execstep.Pos, newSyntaxError("Command not found in PATH", executable))
+	}
+}
+
+// templates Mark variables read by templates as used
+func (v *validator) templates(templates []string) {
+	for _, template := range templates {
+		names, _ := data.TemplateVars(template)
+		for _, name := range names {
+			v.used[name] = true
+		}
+	}
+}
+
+// isInput Check if a variable is a pipeline input
+func (v *validator) isInput(name string) bool {
+	for _, input := range v.pipeline.Input {
+		if input.Name == name {
+			return true
+		}
+	}
+	return false
+}
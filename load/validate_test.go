@@ -0,0 +1,58 @@
+// Copyright (c) 2020 Aritz Olea
+// This file is part of Simplepipe <https://github.com/aritzz/simplepipe>
+//
+// Simplepipe is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Simplepipe is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Simplepipe.  If not, see <https://www.gnu.org/licenses/>.
+
+package load
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestValidateCommands(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a unix shell")
+	}
+
+	tests := []struct {
+		name    string
+		shell   string
+		step    string
+		warning string
+	}{
+		{"default shell", "", "sh (echo hi)", ""},
+		{"configured shell", "shell /bin/sh", "sh (echo hi)", ""},
+		{"missing shell", "shell /nonexistent/sh", "sh (echo hi)", "Shell not found: /nonexistent/sh"},
+		{"missing shell name", "shell nonexistent-sh", "sh (echo hi)", "Shell not found: nonexistent-sh"},
+		{"missing command", "", "(nonexistent-command)", "Command not found in PATH: nonexistent-command"},
+		{"missing absolute command", "", "(/nonexistent/command)", "Command not found: /nonexistent/command"},
+		{"relative command", "", "(./command)", ""},
+	}
+
+	for _, test := range tests {
+		diags := ValidateString("test.pipe", "pipeline commands\n  "+test.shell+"\nbegin\n  "+test.step+"\nend\n")
+		var messages []string
+		for _, diag := range diags {
+			messages = append(messages, diag.Message)
+		}
+		if len(test.warning) == 0 && len(diags) > 0 {
+			t.Errorf("%s: got %q, want no diagnostics", test.name, messages)
+		}
+		if len(test.warning) > 0 && (len(diags) != 1 || diags[0].Severity != SEVERITY_WARNING || !strings.Contains(diags[0].Message, test.warning)) {
+			t.Errorf("%s: got %q, want warning %q", test.name, messages, test.warning)
+		}
+	}
+}
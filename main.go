@@ -19,6 +19,13 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 
 	"github.com/aritzz/simplepipe/load"
 	"github.com/aritzz/simplepipe/pipe"
@@ -29,23 +36,72 @@ const VERSION = "1.0.1"
 // main Main function for Simplepipe software
 func main() {
 
-	pipelineFile := flag.String("pipeline", "", "pipeline file")
-	showArgs := flag.Bool("args", false, "get pipeline argument list")
-	timeExec := flag.Bool("time", false, "get global execution time")
-	timeExecCmd := flag.Bool("timecmd", false, "get execution time for each command")
-	fileLogger := flag.String("logfile", "", "redirect logging to a file")
-	onlyOutput := flag.Bool("outputonly", false, "get only output information")
-	flag.Parse()
+	// Subcommands
+	if len(os.Args) > 1 && os.Args[1] == "validate" {
+		os.Exit(runValidate(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "fmt" {
+		os.Exit(runFormat(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "help" {
+		os.Exit(runHelp(os.Args[2:]))
+	}
+
+	os.Exit(runPipeline(os.Args[1:]))
+}
+
+// runPipeline Load a pipeline and run it, as set by the command
+// line flags. Returns the exit code
+func runPipeline(args []string) int {
+	flags := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	pipelineFile := flags.String("pipeline", "", "pipeline file")
+	showArgs := flags.Bool("args", false, "get pipeline argument list")
+	timeExec := flags.Bool("time", false, "get global execution time")
+	timeExecCmd := flags.Bool("timecmd", false, "get execution time for each command")
+	fileLogger := flags.String("logfile", "", "redirect logging to a file")
+	onlyOutput := flags.Bool("outputonly", false, "get only output information")
+	timeout := flags.Duration("timeout", 0, "maximum execution time for the whole pipeline (e.g. 10m)")
+	keepTemp := flags.Bool("keep-temp", false, "keep temporary files and directories after the run")
+	inputFile := flags.String("input-file", "", "read inputs from a JSON or YAML file")
+	envFile := flags.String("env-file", "", "read inputs from a file of NAME=value lines")
+	noInput := flags.Bool("no-input", false, "never ask for missing inputs, even in a terminal")
+	quiet := flags.Bool("quiet", false, "don't show the output of steps nor the log")
+	verbose := flags.Bool("verbose", false, "show the output of steps that is assigned to variables too")
+	captureLimit := flags.Int64("capture-limit", pipe.CAPTURE_LIMIT, "maximum bytes of output assigned to a variable")
+	reportFile := flags.String("report", "", "write a JSON report of the run to a file")
+	outputFormat := flags.String("output-format", "text", "format of the returned values: "+strings.Join(OUTPUT_FORMATS, ", ")+" (implies -outputonly)")
+	flags.Usage = func() { printUsage(flags) }
+	if err := flags.Parse(args); err != nil {
+		return parseErrorCode(err)
+	}
+
+	if *outputFormat != "text" {
+		*onlyOutput = true
+	}
+
+	// With -outputonly stdout only gets the output, messages go to stderr
+	messages := io.Writer(os.Stdout)
+	if *onlyOutput {
+		messages = os.Stderr
+	}
+
+	if _, err := formatOutput(nil, *outputFormat); err != nil {
+		fmt.Fprintln(messages, err)
+		return EXIT_USAGE
+	}
 
 	// Parse pipeline file
 	if len(*pipelineFile) == 0 {
-		fmt.Println("Pipeline not provided. Use -h to get help.")
-		return
+		fmt.Fprintln(messages, "Pipeline not provided. Use -h to get help.")
+		return EXIT_USAGE
 	}
 	data, err := load.ParseFile(*pipelineFile)
-	if err != nil {
-		fmt.Println("Error parsing pipeline file: ", err)
-		return
+	if diags, ok := err.(load.Diagnostics); ok {
+		fmt.Fprint(os.Stderr, diags.Format())
+		return EXIT_LOAD
+	} else if err != nil {
+		fmt.Fprintln(messages, "Error parsing pipeline file: ", err)
+		return EXIT_LOAD
 	}
 
 	if !*onlyOutput {
@@ -53,46 +109,91 @@ func main() {
 		fmt.Println("Pipeline '" + data.Name + "' loaded")
 	}
 
-	argCount := len(data.Input)
-	userArgCount := len(flag.Args())
+	// Load input data, each source only sets the inputs the previous ones didn't.
+	// A terminal is never read as input, missing inputs are asked instead
+	terminal := isTerminal(os.Stdin)
+	err = loadInputs(&data, flags.Args(), *inputFile, *envFile, !*showArgs && !terminal)
+	if err == nil && terminal && !*noInput && !*showArgs {
+		promptInputs(&data)
+	}
 
 	// See arguments to be provided
 	if *showArgs {
-		fmt.Print("You must provide ", argCount, " argument(s):")
-		for _, value := range data.Input {
-			if len(value.Value) == 0 {
-				fmt.Print(" <", value.Name, ">")
-			} else {
-				fmt.Print(" <", value.Value, ">")
-			}
-		}
-		fmt.Print("\n")
-		return
+		pipe.ResolveInput(&data)
+		printInputs(data)
+		return EXIT_OK
 	}
 
-	if argCount != userArgCount {
-		fmt.Println("Invalid argument count. ", userArgCount, "provided,", argCount, "needed.")
-		return
+	if err == nil {
+		err = pipe.ResolveInput(&data)
+	}
+	if err != nil {
+		fmt.Fprintln(messages, err)
+		return EXIT_USAGE
 	}
-
-	// Load input data
-	pipe.LoadInput(&data, flag.Args())
 
 	if !*onlyOutput {
 		fmt.Println("Executing pipeline")
 	}
 
+	// Command line timeout overrides the pipeline one
+	if *timeout > 0 {
+		data.Timeout = *timeout
+	}
+
+	data.KeepTemp = *keepTemp
+
+	// Log to the standard error, or to the log file with the output of every step
+	logOutput := io.Writer(os.Stderr)
+	if *quiet {
+		logOutput = ioutil.Discard
+	}
+	options := []pipe.Option{pipe.WithInterrupt(notifyInterrupt()), pipe.WithCaptureLimit(*captureLimit)}
+	if len(*fileLogger) > 0 {
+		logfile, err := pipe.OpenLogFile(*fileLogger)
+		if err != nil {
+			fmt.Fprintln(messages, err)
+			return EXIT_USAGE
+		}
+		defer logfile.Close()
+		logOutput = logfile
+		options = append(options, pipe.WithOutputLogging())
+	}
+	options = append(options, pipe.WithLogger(log.New(logOutput, "", log.LstdFlags)))
+
+	// Stream the output of steps, stdout is kept for the returned values if only they are printed
+	if !*quiet {
+		stdout := io.Writer(os.Stdout)
+		if *onlyOutput {
+			stdout = os.Stderr
+		}
+		options = append(options, pipe.WithStdout(stdout), pipe.WithStderr(os.Stderr))
+	}
+	if *verbose {
+		options = append(options, pipe.WithVerbose())
+	}
+
 	// Execute pipeline
-	pipelineOutput, err := pipe.ExecutePipeline(data, *fileLogger)
+	runner := pipe.NewRunner(options...)
+	pipelineOutput, err := runner.Run(data)
 
 	if err != nil {
-		fmt.Println(err)
+		fmt.Fprintln(messages, err)
+		printFailedSteps(pipelineOutput)
+	}
+	printFailedDeferred(pipelineOutput)
+	if len(pipelineOutput.TempDir) > 0 {
+		fmt.Fprintln(os.Stderr, "Temporary files kept in", pipelineOutput.TempDir)
 	}
 
-	if !*onlyOutput {
-		fmt.Println("Pipeline output: " + pipelineOutput.Output)
+	output, _ := formatOutput(pipelineOutput.Output, *outputFormat)
+	if *onlyOutput {
+		fmt.Println(output)
+	} else if len(pipelineOutput.Output) > 1 {
+		fmt.Println("Pipeline output:")
+		fmt.Println("  " + strings.ReplaceAll(output, "\n", "\n  "))
 	} else {
-		fmt.Println(pipelineOutput.Output)
+		fmt.Println("Pipeline output: " + output)
 	}
 
 	// Print execution times if needed
@@ -103,4 +204,36 @@ func main() {
 	if *timeExecCmd {
 		printExectimeFunction(pipelineOutput)
 	}
+
+	code := exitCode(pipelineOutput, err)
+	if len(*reportFile) > 0 {
+		if reportErr := pipe.NewReport(pipelineOutput, err).WriteFile(*reportFile); reportErr != nil {
+			fmt.Fprintln(os.Stderr, "Error writing report:", reportErr)
+			if code == EXIT_OK {
+				code = EXIT_FAILURE
+			}
+		}
+	}
+
+	return code
+}
+
+// notifyInterrupt Get an interrupt for SIGINT and SIGTERM. The first signal
+// is forwarded to the running steps and cleanup runs, the second one kills them
+func notifyInterrupt() *pipe.Interrupt {
+	interrupt := pipe.NewInterrupt()
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		sig := <-signals
+		fmt.Fprintln(os.Stderr, "Interrupted by "+sig.String()+", stopping the pipeline (again to kill running steps)")
+		interrupt.Signal(sig)
+
+		sig = <-signals
+		fmt.Fprintln(os.Stderr, "Interrupted again, killing running steps")
+		interrupt.Signal(sig)
+	}()
+
+	return interrupt
 }
@@ -0,0 +1,82 @@
+// Copyright (c) 2020 Aritz Olea
+// This file is part of Simplepipe <https://github.com/aritzz/simplepipe>
+//
+// Simplepipe is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Simplepipe is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Simplepipe.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/aritzz/simplepipe/data"
+)
+
+// OUTPUT_FORMATS are the formats of -output-format
+var OUTPUT_FORMATS = []string{"text", "json", "env", "shell"}
+
+// formatOutput Get the values returned by a pipeline in a format.
+// Text is the value when there is only one, or a name: value line each
+func formatOutput(outputs []data.PipelineResultOutput, format string) (string, error) {
+	var lines []string
+
+	switch format {
+	case "text":
+		if len(outputs) == 1 {
+			return outputs[0].Value, nil
+		}
+		for _, output := range outputs {
+			lines = append(lines, output.Name+": "+output.Value)
+		}
+	case "json":
+		for _, output := range outputs {
+			name, _ := json.Marshal(output.Name)
+			value, _ := json.Marshal(output.Value)
+			lines = append(lines, "  "+string(name)+": "+string(value))
+		}
+		if len(lines) == 0 {
+			return "{}", nil
+		}
+		return "{\n" + strings.Join(lines, ",\n") + "\n}", nil
+	case "env":
+		for _, output := range outputs {
+			lines = append(lines, output.Name+"="+envQuote(output.Value))
+		}
+	case "shell":
+		for _, output := range outputs {
+			lines = append(lines, output.Name+"="+shellQuote(output.Value))
+		}
+	default:
+		return "", errors.New("Unknown output format: " + format + ", use " + strings.Join(OUTPUT_FORMATS, ", "))
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+// envQuote Quote a value for a .env file, as read by -env-file
+func envQuote(value string) string {
+	if regexp.MustCompile(`^[\w./:@%+,=-]+$`).MatchString(value) {
+		return value
+	}
+
+	replacer := strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "\n", "\\n", "\t", "\\t")
+	return "\"" + replacer.Replace(value) + "\""
+}
+
+// shellQuote Quote a value for a POSIX shell
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "'\\''") + "'"
+}
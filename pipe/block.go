@@ -0,0 +1,162 @@
+// Copyright (c) 2020 Aritz Olea
+// This file is part of Simplepipe <https://github.com/aritzz/simplepipe>
+//
+// Simplepipe is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Simplepipe is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Simplepipe.  If not, see <https://www.gnu.org/licenses/>.
+
+package pipe
+
+import (
+	"os/exec"
+
+	"github.com/aritzz/simplepipe/data"
+)
+
+// execStepIf Execute the first branch whose condition is true,
+// steps in every other branch are recorded as skipped
+func execStepIf(state *execState, execstep data.PipelineExecution) error {
+	var err error
+	taken := -1
+
+	for i, branch := range execstep.Branches {
+		isTrue := true
+		if branch.Condition != nil {
+			isTrue, err = evalCondition(state, *branch.Condition)
+			if err != nil {
+				return err
+			}
+		}
+		if isTrue {
+			taken = i
+			break
+		}
+	}
+
+	for i, branch := range execstep.Branches {
+		if i != taken {
+			skipBlock(state, branch.Body)
+			continue
+		}
+		if err = execBlock(state, branch.Body); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// evalCondition Evaluate an if condition
+func evalCondition(state *execState, condition data.PipelineCondition) (bool, error) {
+	var isTrue bool
+	var err error
+	var left, right string
+
+	switch condition.Type {
+	case data.COND_EXEC:
+		isTrue, err = execStepCondition(state, condition.Exec)
+	case data.COND_EMPTY:
+		left, err = cmdReplaceVars(state.vars, condition.Left)
+		isTrue = len(left) == 0
+	case data.COND_EQUAL, data.COND_NOTEQUAL:
+		if left, err = cmdReplaceVars(state.vars, condition.Left); err != nil {
+			break
+		}
+		if right, err = cmdReplaceVars(state.vars, condition.Right); err != nil {
+			break
+		}
+		isTrue = (left == right) == (condition.Type == data.COND_EQUAL)
+	}
+
+	if condition.Negate {
+		isTrue = !isTrue
+	}
+	if err == nil {
+		state.runner.logger.Println("Condition is", isTrue)
+	}
+
+	return isTrue, err
+}
+
+// execStepCondition Execute a condition command, it is true when it exits with status 0
+func execStepCondition(state *execState, execstep data.PipelineExecution) (bool, error) {
+	var isTrue bool
+	var step data.PipelineResultExecStep
+	result := commandResult{ExitCode: -1}
+
+	state.runner.logger.Println("Checking [", execstep.Command, "]")
+
+	// Exec time
+	step.Start = state.runner.clock.Now()
+
+	// Replace values
+	commandexec, command, err := stepArgs(state.vars, execstep)
+	if err != nil {
+		goto condEnd
+	}
+
+	// Execute command, a non zero exit status is not an error here
+	result, err = runCommand(state, execstep.Timeout, commandexec, commandEnv(state, execstep.Mode), commandOutput{Number: state.counter.next(), Command: execstep.Command})
+	if _, ok := err.(*exec.ExitError); ok {
+		err = nil
+		goto condEnd
+	}
+	isTrue = err == nil
+
+condEnd:
+	step.Command = command
+	step.Mode = execstep.Mode
+	setStepOutput(&step, result)
+	if err != nil {
+		state.runner.logger.Println("Execution error ", err.Error())
+	}
+	state.addStep(step, err)
+
+	return isTrue, err
+}
+
+// skipBlock Record every step in a block as skipped
+func skipBlock(state *execState, block data.PipelineBlock) {
+	for _, execItem := range block {
+		switch execItem.Type {
+		case data.TYPE_IF:
+			for _, branch := range execItem.Branches {
+				if branch.Condition != nil && branch.Condition.Type == data.COND_EXEC {
+					skipStep(state, branch.Condition.Exec)
+				}
+				skipBlock(state, branch.Body)
+			}
+		case data.TYPE_FOREACH:
+			if execItem.Loop.Type == data.LOOP_EXEC {
+				skipStep(state, execItem.Loop.Exec)
+			}
+			skipBlock(state, execItem.Body)
+		case data.TYPE_PARALLEL:
+			skipBlock(state, execItem.Body)
+		case data.TYPE_TRY:
+			skipBlock(state, execItem.Body)
+			skipBlock(state, execItem.Try.Catch)
+			skipBlock(state, execItem.Try.Finally)
+		case data.TYPE_DEFER:
+			// Never registered, so it never runs
+		default:
+			skipStep(state, execItem)
+		}
+	}
+}
+
+// skipStep Record a single step as skipped
+func skipStep(state *execState, execstep data.PipelineExecution) {
+	state.runner.logger.Println("Skipping [", execstep.Command, "]")
+	step := data.PipelineResultExecStep{Command: execstep.Command, Mode: execstep.Mode, Skipped: true}
+	state.steps = append(state.steps, step)
+}
@@ -0,0 +1,91 @@
+// Copyright (c) 2020 Aritz Olea
+// This file is part of Simplepipe <https://github.com/aritzz/simplepipe>
+//
+// Simplepipe is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Simplepipe is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Simplepipe.  If not, see <https://www.gnu.org/licenses/>.
+
+package pipe
+
+import (
+	"github.com/aritzz/simplepipe/data"
+)
+
+// deferredStep A command registered by defer, arguments and
+// environment are taken when it is registered
+type deferredStep struct {
+	step    data.PipelineExecution
+	args    []string
+	command string
+	env     []string
+}
+
+// execStepDefer Register a command to run when the pipeline ends
+func execStepDefer(state *execState, execstep data.PipelineExecution) error {
+	var step data.PipelineResultExecStep
+
+	step.Start = state.runner.clock.Now()
+	commandexec, command, err := stepArgs(state.vars, execstep)
+	if err != nil {
+		step.Command = execstep.Command
+		step.Mode = execstep.Mode
+		state.addStep(step, err)
+		state.runner.logger.Println("Execution error ", err.Error())
+		return err
+	}
+
+	state.runner.logger.Println("Deferring [", execstep.Command, "]")
+	state.deferred = append(state.deferred, deferredStep{execstep, commandexec, command, commandEnv(state, execstep.Mode)})
+
+	return nil
+}
+
+// execDeferred Run deferred commands, last registered first. Every command
+// runs even if a previous one fails, and their errors are only logged so
+// they never hide the result of the pipeline
+func execDeferred(state *execState) []data.PipelineResultExecStep {
+	cleanupctx, cancel := cleanupContext(state)
+	defer cancel()
+
+	cleanup := &execState{runner: state.runner, counter: state.counter, ctx: cleanupctx, vars: state.vars}
+	for i := len(state.deferred) - 1; i >= 0; i-- {
+		if cleanupctx.Err() != nil {
+			state.runner.logger.Println("Deferred commands stopped:", contextError(cleanupctx).Error())
+			break
+		}
+		execDeferredStep(cleanup, state.deferred[i])
+	}
+
+	return cleanup.steps
+}
+
+// execDeferredStep Run a single deferred command
+func execDeferredStep(state *execState, deferred deferredStep) {
+	var step data.PipelineResultExecStep
+
+	state.runner.logger.Println("Running deferred [", deferred.step.Command, "]")
+
+	step.Start = state.runner.clock.Now()
+	result, attempts, err := execRetry(state, deferred.step, deferred.args, deferred.env, false)
+
+	step.Command = deferred.command
+	step.Mode = deferred.step.Mode
+	step.Attempts = attempts
+	setStepOutput(&step, result)
+	state.addStep(step, err)
+
+	if err != nil {
+		state.runner.logger.Println("Deferred execution error ", err.Error())
+	} else {
+		state.runner.logger.Println("Finished in ", state.steps[len(state.steps)-1].ExecTime)
+	}
+}
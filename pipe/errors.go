@@ -0,0 +1,107 @@
+// Copyright (c) 2020 Aritz Olea
+// This file is part of Simplepipe <https://github.com/aritzz/simplepipe>
+//
+// Simplepipe is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Simplepipe is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Simplepipe.  If not, see <https://www.gnu.org/licenses/>.
+
+package pipe
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/aritzz/simplepipe/data"
+)
+
+// ErrCancelled is returned by steps stopped before finishing,
+// like the other steps of a failed parallel block
+var ErrCancelled = errors.New("cancelled")
+
+// TimeoutError is returned when a step or the whole pipeline
+// runs out of time. Timeout is zero for the pipeline deadline
+type TimeoutError struct {
+	Timeout time.Duration
+}
+
+func (err *TimeoutError) Error() string {
+	if err.Timeout == 0 {
+		return "pipeline timeout reached"
+	}
+	return "step timed out after " + err.Timeout.String()
+}
+
+// StepError is returned when a failed step stops the pipeline, it wraps
+// the error of the step. ExitCode is the one of the step command, -1 if it
+// never exited by itself: it could not start, timed out or got a signal
+type StepError struct {
+	Command  string
+	ExitCode int
+	Signal   string
+	Err      error
+}
+
+func (err *StepError) Error() string {
+	return err.Err.Error()
+}
+
+func (err *StepError) Unwrap() error {
+	return err.Err
+}
+
+// InputError is returned when pipeline inputs are missing, unknown
+// or don't meet their constraints. It has every problem found
+type InputError struct {
+	Problems []string
+}
+
+func (err *InputError) Error() string {
+	return strings.Join(err.Problems, "\n")
+}
+
+// contextError Get the error for a finished context
+func contextError(ctx context.Context) error {
+	if ctx.Err() == context.DeadlineExceeded {
+		return &TimeoutError{}
+	}
+	return ErrCancelled
+}
+
+// stepError Wrap the error that stopped a pipeline with the details
+// of the last failed step with the same kind of error, if there is one
+func stepError(err error, steps []data.PipelineResultExecStep) error {
+	kind := errorKind(err)
+	for i := len(steps) - 1; i >= 0; i-- {
+		step := steps[i]
+		if len(step.Error) > 0 && !step.Ignored && step.ErrorKind == kind {
+			return &StepError{Command: step.Command, ExitCode: step.ExitCode, Signal: step.Signal, Err: err}
+		}
+	}
+	return err
+}
+
+// errorKind Get the kind of a step error
+func errorKind(err error) data.ErrorKind {
+	var timeout *TimeoutError
+
+	switch {
+	case err == nil:
+		return data.ERROR_NONE
+	case errors.As(err, &timeout):
+		return data.ERROR_TIMEOUT
+	case errors.Is(err, ErrCancelled):
+		return data.ERROR_CANCELLED
+	}
+	return data.ERROR_FAILED
+}
@@ -14,42 +14,301 @@
 // You should have received a copy of the GNU General Public License
 // along with Simplepipe.  If not, see <https://www.gnu.org/licenses/>.
 
-
 package pipe
 
-import(
-  "strings"
-  "bytes"
-  "os/exec"
-  "os"
+import (
+	"context"
+	"errors"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aritzz/simplepipe/data"
 )
 
+// KILL_GRACE is the time a process group gets to exit after SIGTERM
+const KILL_GRACE = 5 * time.Second
+
+// STDERR_TAIL is how many bytes of stderr are kept for each attempt
+const STDERR_TAIL = 2048
+
+// OUTPUT_LIMIT is how many bytes of stdout and stderr are kept in step results
+const OUTPUT_LIMIT = 64 * 1024
+
+// commandResult Details of a finished command. Captured output is
+// complete, otherwise Stdout has the first OUTPUT_LIMIT bytes and
+// Stderr the last ones. StdoutBytes and StderrBytes are the whole sizes
+type commandResult struct {
+	Number      int
+	Stdout      string
+	Stderr      string
+	StdoutBytes int64
+	StderrBytes int64
+	ExitCode    int
+	Signal      string
+}
+
+// commandOutput How the output of a command is handled. Number is the
+// step number streamed lines are prefixed with, and Command the step
+// command as written, logged with it so values are never logged. Stdout
+// and Stderr are set when they are captured to be assigned to variables
+type commandOutput struct {
+	Number  int
+	Command string
+	Stdout  bool
+	Stderr  bool
+}
+
+// outputBuffer Writer that keeps the output of a command
+type outputBuffer interface {
+	io.Writer
+	String() string
+}
+
+// runCommand Run a command in its own process group, in the runner
+// directory. Its output is streamed as the runner is set to, and captured
+// up to the runner capture limit. When the state context ends or the timeout
+// expires the whole group gets SIGTERM, or the signal that interrupted the
+// run, and SIGKILL if it is still running after KILL_GRACE or after a second
+// signal. Output kept open by children that outlive it is left after KILL_GRACE
+func runCommand(state *execState, timeout time.Duration, commandWithArgs []string, env []string, output commandOutput) (commandResult, error) {
+	var err error
+	ctx := state.ctx
+	result := commandResult{Number: output.Number, ExitCode: -1}
+
+	outcount, errcount := &byteCounter{}, &byteCounter{}
+	outbuffer := newOutputBuffer(state.runner, output.Stdout, &headBuffer{size: OUTPUT_LIMIT})
+	errbuffer := newOutputBuffer(state.runner, output.Stderr, &tailBuffer{size: OUTPUT_LIMIT})
+	outstreams := state.runner.streams(output.Number, output.Stdout, state.runner.stdout)
+	errstreams := state.runner.streams(output.Number, output.Stderr, state.runner.stderr)
+	defer flushStreams(outstreams)
+	defer flushStreams(errstreams)
+
+	stepctx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		stepctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	stdout, err := newOutputPipe(teeWriter(outbuffer, outcount, outstreams))
+	if err != nil {
+		return result, err
+	}
+	stderr, err := newOutputPipe(teeWriter(errbuffer, errcount, errstreams))
+	if err != nil {
+		stdout.close()
+		return result, err
+	}
+	copied := copyDone(stdout, stderr)
 
-func execCommandOutput(command string) (string, error) {
-  commandWithArgs := strings.Split(command, " ")
+	cmd := exec.Command(commandWithArgs[0], commandWithArgs[1:]...)
+	cmd.Env = env
+	cmd.Dir = state.runner.dir
+	cmd.Stdout = stdout.writer
+	cmd.Stderr = stderr.writer
+	setProcessGroup(cmd)
 
-	var out bytes.Buffer
-  cmd := exec.Command(commandWithArgs[0], commandWithArgs[1:]...)
-  cmd.Env = os.Environ()
-	cmd.Stdout = &out
-	err := cmd.Run()
+	err = cmd.Start()
+	stdout.writer.Close()
+	stderr.writer.Close()
 	if err != nil {
-		return out.String(), err
+		<-copied
+		return result, err
+	}
+	state.runner.logger.Println(stepPrefix(output.Number)+"Started [", output.Command, "]")
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	// The step ends when the command exits and its output is closed
+	exited := false
+	select {
+	case err = <-done:
+		exited = true
+		select {
+		case <-copied:
+			goto cmdEnd
+		case <-stepctx.Done():
+		}
+	case <-stepctx.Done():
+	}
+
+	// Interrupted runs forward the signal, a second one kills at once
+	if sig := state.runner.interrupt.Received(); sig != nil && ctx.Err() != nil {
+		signalProcessGroup(cmd, sig)
+	} else {
+		terminateProcessGroup(cmd)
+	}
+	if !exited {
+		select {
+		case <-done:
+		case <-state.runner.interrupt.killedChan():
+			killProcessGroup(cmd)
+			<-done
+		case <-time.After(KILL_GRACE):
+			killProcessGroup(cmd)
+			<-done
+		}
+	}
+
+	// Children out of the group can keep the output open, it is left then
+	select {
+	case <-copied:
+	case <-time.After(KILL_GRACE):
+		killProcessGroup(cmd)
+		stdout.close()
+		stderr.close()
+		<-copied
+	}
+
+	// Tell apart our own timeout from the pipeline ones
+	if ctx.Err() == nil {
+		err = &TimeoutError{Timeout: timeout}
+	} else {
+		err = contextError(ctx)
+	}
+
+cmdEnd:
+	result.ExitCode = cmd.ProcessState.ExitCode()
+	result.Signal = exitSignal(cmd.ProcessState)
+	result.Stdout = outbuffer.String()
+	result.Stderr = errbuffer.String()
+	result.StdoutBytes = outcount.count
+	result.StderrBytes = errcount.count
+	if output.Stdout {
+		result.Stdout = strings.TrimSuffix(result.Stdout, "\n")
+	}
+	if err == nil && (captureExceeded(outbuffer) || captureExceeded(errbuffer)) {
+		err = errors.New("Captured output is larger than " + strconv.FormatInt(state.runner.captureLimit, 10) + " bytes")
+	}
+	return result, err
+}
+
+// newOutputBuffer Get the buffer for an output, capped unless it is captured
+func newOutputBuffer(runner *Runner, captured bool, capped outputBuffer) outputBuffer {
+	if captured {
+		return &limitBuffer{limit: runner.captureLimit}
 	}
+	return capped
+}
 
-  return strings.TrimSuffix(out.String(), "\n"), nil
+// captureExceeded Check if a buffer has dropped captured output
+func captureExceeded(buffer outputBuffer) bool {
+	capture, ok := buffer.(*limitBuffer)
+	return ok && capture.exceeded
 }
 
-func execCommand(command string) (error) {
-  commandWithArgs := strings.Split(command, " ")
+// teeWriter Get a writer for an output buffer, its byte counter and its streams
+func teeWriter(buffer outputBuffer, counter *byteCounter, streams []*lineWriter) io.Writer {
+	writers := []io.Writer{buffer, counter}
+	for _, stream := range streams {
+		writers = append(writers, stream)
+	}
+	return io.MultiWriter(writers...)
+}
 
-  cmd := exec.Command(commandWithArgs[0], commandWithArgs[1:]...)
-  cmd.Env = os.Environ()
+// flushStreams Emit the last line of every stream
+func flushStreams(streams []*lineWriter) {
+	for _, stream := range streams {
+		stream.Flush()
+	}
+}
 
-	err := cmd.Run()
-	if err != nil {
-		return err
+// setStepOutput Copy command details to a step result
+func setStepOutput(step *data.PipelineResultExecStep, result commandResult) {
+	step.Number = result.Number
+	step.ExitCode = result.ExitCode
+	step.Signal = result.Signal
+	step.Stdout = head(result.Stdout, OUTPUT_LIMIT)
+	step.Stderr = tail(result.Stderr, OUTPUT_LIMIT)
+	step.StdoutBytes = result.StdoutBytes
+	step.StderrBytes = result.StderrBytes
+}
+
+// tailBuffer Writer that keeps only the last size bytes written
+type tailBuffer struct {
+	size int
+	data []byte
+}
+
+func (buffer *tailBuffer) Write(p []byte) (int, error) {
+	buffer.data = append(buffer.data, p...)
+	if len(buffer.data) > buffer.size {
+		buffer.data = buffer.data[len(buffer.data)-buffer.size:]
+	}
+	return len(p), nil
+}
+
+func (buffer *tailBuffer) String() string {
+	return string(buffer.data)
+}
+
+// headBuffer Writer that keeps only the first size bytes written
+type headBuffer struct {
+	size int
+	data []byte
+}
+
+func (buffer *headBuffer) Write(p []byte) (int, error) {
+	if room := buffer.size - len(buffer.data); room > 0 {
+		if len(p) < room {
+			room = len(p)
+		}
+		buffer.data = append(buffer.data, p[:room]...)
 	}
+	return len(p), nil
+}
 
-  return nil
+func (buffer *headBuffer) String() string {
+	return string(buffer.data)
+}
+
+// tail Get the last bytes of a string
+func tail(value string, size int) string {
+	if len(value) > size {
+		return value[len(value)-size:]
+	}
+	return value
+}
+
+// head Get the first bytes of a string
+func head(value string, size int) string {
+	if len(value) > size {
+		return value[:size]
+	}
+	return value
+}
+
+// commandEnv Get environment for a command, the one of the runner.
+// shell steps get every pipeline variable exported, so values reach the
+// shell as data and never as shell syntax
+func commandEnv(state *execState, mode data.ExecutionMode) []string {
+	env := state.runner.environ()
+	if mode != data.MODE_SHELL {
+		return env
+	}
+
+	for key, value := range state.vars.snapshot() {
+		env = append(env, key+"="+value)
+	}
+
+	return env
+}
+
+// commandString Get a printable form of an argument list
+func commandString(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if len(arg) == 0 || strings.ContainsAny(arg, " \t\n\"'\\") {
+			quoted[i] = strconv.Quote(arg)
+		} else {
+			quoted[i] = arg
+		}
+	}
+	return strings.Join(quoted, " ")
 }
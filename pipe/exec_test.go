@@ -0,0 +1,60 @@
+// Copyright (c) 2020 Aritz Olea
+// This file is part of Simplepipe <https://github.com/aritzz/simplepipe>
+//
+// Simplepipe is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Simplepipe is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Simplepipe.  If not, see <https://www.gnu.org/licenses/>.
+
+package pipe_test
+
+import (
+	"errors"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/aritzz/simplepipe/load"
+	"github.com/aritzz/simplepipe/pipe"
+)
+
+// TestTimeoutOpenOutput Check that a step times out even if a child
+// out of its process group keeps its output open
+func TestTimeoutOpenOutput(t *testing.T) {
+	if testing.Short() {
+		t.Skip("waits for the kill grace period")
+	}
+	if _, err := exec.LookPath("setsid"); err != nil {
+		t.Skip("setsid not found")
+	}
+
+	pipeline, err := load.ParseString("test.pipe", `pipeline open
+begin
+  sh (setsid sleep 60 & echo started) timeout 1s
+end
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	result, err := pipe.NewRunner().Run(pipeline)
+	var timeout *pipe.TimeoutError
+	if !errors.As(err, &timeout) {
+		t.Errorf("got error %v, want a timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 1*time.Second+pipe.KILL_GRACE+time.Second {
+		t.Errorf("step took %v", elapsed)
+	}
+	if len(result.ExecStep) != 1 || result.ExecStep[0].Stdout != "started\n" {
+		t.Errorf("got steps %+v", result.ExecStep)
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright (c) 2020 Aritz Olea
+// This file is part of Simplepipe <https://github.com/aritzz/simplepipe>
+//
+// Simplepipe is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Simplepipe is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Simplepipe.  If not, see <https://www.gnu.org/licenses/>.
+
+package pipe
+
+import (
+	"strings"
+
+	"github.com/aritzz/simplepipe/data"
+)
+
+// SetInput Set pipeline inputs from command line arguments. Arguments
+// like --name value or --name=value set an input by name, the others
+// set the inputs not given by name, in order
+func SetInput(pipeline *data.Pipeline, args []string) error {
+	var problems []string
+	var positional []string
+
+	for i := 0; i < len(args); i++ {
+		if !strings.HasPrefix(args[i], "--") || len(args[i]) == 2 {
+			positional = append(positional, args[i])
+			continue
+		}
+
+		name, value := args[i][2:], ""
+		if eq := strings.IndexByte(name, '='); eq >= 0 {
+			name, value = name[:eq], name[eq+1:]
+		} else if i+1 < len(args) {
+			i++
+			value = args[i]
+		} else {
+			problems = append(problems, "Missing value for input "+name)
+			continue
+		}
+
+		input := findInput(pipeline.Input, name)
+		switch {
+		case input == nil:
+			problems = append(problems, "Unknown input "+name)
+		case input.Source != data.SOURCE_NONE:
+			problems = append(problems, "Input "+name+" is given twice")
+		default:
+			input.Value = value
+			input.Source = data.SOURCE_ARGS
+		}
+	}
+
+	for i := range pipeline.Input {
+		if len(positional) == 0 {
+			break
+		}
+		if pipeline.Input[i].Source == data.SOURCE_NONE {
+			pipeline.Input[i].Value = positional[0]
+			pipeline.Input[i].Source = data.SOURCE_ARGS
+			positional = positional[1:]
+		}
+	}
+	if len(positional) > 0 {
+		problems = append(problems, "Too many arguments: "+strings.Join(positional, " "))
+	}
+
+	if len(problems) > 0 {
+		return &InputError{Problems: problems}
+	}
+	return nil
+}
+
+// findInput Get an input by name, nil if it is not declared
+func findInput(inputs []data.PipelineInput, name string) *data.PipelineInput {
+	for i := range inputs {
+		if inputs[i].Name == name {
+			return &inputs[i]
+		}
+	}
+	return nil
+}
+
+// checkInput Check every input before the pipeline runs, relative file
+// paths are looked up in dir. Returns a copy of the inputs where the
+// ones not given take their default value
+func checkInput(inputs []data.PipelineInput, dir string) ([]data.PipelineInput, error) {
+	var problems []string
+	checked := append([]data.PipelineInput{}, inputs...)
+
+	for i, input := range checked {
+		// Inputs set without SetInput count as given if they have a value
+		if input.Source == data.SOURCE_NONE && len(input.Value) == 0 {
+			if !input.HasDefault {
+				problems = append(problems, "Missing input "+input.Name)
+				continue
+			}
+			checked[i].Value = input.Default
+			checked[i].Source = data.SOURCE_DEFAULT
+		}
+		if err := input.CheckValueIn(checked[i].Value, dir); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	if len(problems) > 0 {
+		return checked, &InputError{Problems: problems}
+	}
+	return checked, nil
+}
+
+// ResolveInput Set inputs not given to their default value, and check
+// that every required input is set and every value meets its constraints
+func ResolveInput(pipeline *data.Pipeline) error {
+	var err error
+	pipeline.Input, err = checkInput(pipeline.Input, "")
+	return err
+}
@@ -0,0 +1,105 @@
+// Copyright (c) 2020 Aritz Olea
+// This file is part of Simplepipe <https://github.com/aritzz/simplepipe>
+//
+// Simplepipe is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Simplepipe is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Simplepipe.  If not, see <https://www.gnu.org/licenses/>.
+
+package pipe
+
+import (
+	"context"
+	"os"
+	"sync"
+)
+
+// Interrupt stops the pipelines of a Runner when a signal arrives. On the
+// first signal running commands get it and cleanup steps run, on the second
+// one every command still running is killed. It is safe to use from many
+// goroutines, and a nil Interrupt is never signalled
+type Interrupt struct {
+	mutex       sync.Mutex
+	signal      os.Signal
+	count       int
+	interrupted chan struct{}
+	killed      chan struct{}
+}
+
+// NewInterrupt Create an Interrupt, see WithInterrupt
+func NewInterrupt() *Interrupt {
+	return &Interrupt{interrupted: make(chan struct{}), killed: make(chan struct{})}
+}
+
+// Signal Record a signal, the first one interrupts the runs
+// and the second one kills their commands
+func (interrupt *Interrupt) Signal(sig os.Signal) {
+	interrupt.mutex.Lock()
+	defer interrupt.mutex.Unlock()
+
+	interrupt.count++
+	switch interrupt.count {
+	case 1:
+		interrupt.signal = sig
+		close(interrupt.interrupted)
+	case 2:
+		close(interrupt.killed)
+	}
+}
+
+// Received Get the first signal received, nil if there is none
+func (interrupt *Interrupt) Received() os.Signal {
+	if interrupt == nil {
+		return nil
+	}
+
+	interrupt.mutex.Lock()
+	defer interrupt.mutex.Unlock()
+	return interrupt.signal
+}
+
+// interruptedChan Get a channel closed by the first signal
+func (interrupt *Interrupt) interruptedChan() <-chan struct{} {
+	if interrupt == nil {
+		return nil
+	}
+	return interrupt.interrupted
+}
+
+// killedChan Get a channel closed by the second signal
+func (interrupt *Interrupt) killedChan() <-chan struct{} {
+	if interrupt == nil {
+		return nil
+	}
+	return interrupt.killed
+}
+
+// watchContext Get a context that also ends when done is closed
+func watchContext(parent context.Context, done <-chan struct{}) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+	select {
+	case <-done:
+		cancel()
+		return ctx, cancel
+	default:
+	}
+
+	if done != nil {
+		go func() {
+			select {
+			case <-done:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+	}
+	return ctx, cancel
+}
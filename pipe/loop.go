@@ -0,0 +1,136 @@
+// Copyright (c) 2020 Aritz Olea
+// This file is part of Simplepipe <https://github.com/aritzz/simplepipe>
+//
+// Simplepipe is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Simplepipe is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Simplepipe.  If not, see <https://www.gnu.org/licenses/>.
+
+package pipe
+
+import (
+	"strings"
+
+	"github.com/aritzz/simplepipe/data"
+)
+
+// execStepForeach Execute a foreach block once per item, the loop
+// variable only exists while the block runs
+func execStepForeach(state *execState, execstep data.PipelineExecution) error {
+	items, err := getLoopItems(state, *execstep.Loop)
+	if err != nil {
+		return err
+	}
+
+	state.runner.logger.Println("Looping over", len(items), "item(s) as $"+execstep.Output)
+	defer state.vars.remove(execstep.Output)
+	if execstep.Loop.Type == data.LOOP_LIST && state.vars.isSecret(strings.Join(execstep.Loop.Items, " ")) {
+		state.vars.markSecret(execstep.Output)
+	}
+
+	for i, item := range items {
+		state.vars.declare(execstep.Output, item)
+		first := len(state.steps)
+		err = execBlock(state, execstep.Body)
+		setIteration(state.steps[first:], i)
+		if err != nil {
+			break
+		}
+	}
+
+	return err
+}
+
+// getLoopItems Get items to loop over
+func getLoopItems(state *execState, loop data.PipelineLoop) ([]string, error) {
+	var items []string
+
+	switch loop.Type {
+	case data.LOOP_EXEC:
+		return execStepLoop(state, loop.Exec)
+	case data.LOOP_GLOB:
+		pattern, err := cmdReplaceVars(state.vars, loop.Items[0])
+		if err != nil {
+			return items, err
+		}
+		return state.runner.glob(pattern)
+	}
+
+	// A value that is a single variable is a list, one item per line
+	for _, item := range loop.Items {
+		value, err := cmdReplaceVars(state.vars, item)
+		if err != nil {
+			return items, err
+		}
+		names, _ := data.TemplateVars(item)
+		if len(names) == 1 && (item == "$"+names[0] || item == "${"+names[0]+"}") {
+			items = append(items, splitLines(value)...)
+		} else {
+			items = append(items, value)
+		}
+	}
+
+	return items, nil
+}
+
+// execStepLoop Execute a foreach command, every output line is an item
+func execStepLoop(state *execState, execstep data.PipelineExecution) ([]string, error) {
+	var step data.PipelineResultExecStep
+	result := commandResult{ExitCode: -1}
+
+	state.runner.logger.Println("Running [", execstep.Command, "]")
+
+	// Exec time
+	step.Start = state.runner.clock.Now()
+
+	// Replace values
+	commandexec, command, err := stepArgs(state.vars, execstep)
+	if err != nil {
+		goto loopEnd
+	}
+
+	// Execute command
+	result, step.Attempts, err = execRetry(state, execstep, commandexec, commandEnv(state, execstep.Mode), true)
+
+loopEnd:
+	step.Command = command
+	step.Mode = execstep.Mode
+	setStepOutput(&step, result)
+	state.addStep(step, err)
+	if err != nil {
+		state.runner.logger.Println("Execution error ", err.Error())
+	} else {
+		state.runner.logger.Println("Finished in ", state.steps[len(state.steps)-1].ExecTime)
+	}
+
+	return splitLines(result.Stdout), err
+}
+
+// splitLines Split a value into its non empty lines
+func splitLines(value string) []string {
+	var lines []string
+
+	for _, line := range strings.Split(value, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if len(line) > 0 {
+			lines = append(lines, line)
+		}
+	}
+
+	return lines
+}
+
+// setIteration Prepend a loop index to step results
+func setIteration(steps []data.PipelineResultExecStep, i int) {
+	for j := range steps {
+		steps[j].Iteration = append([]int{i}, steps[j].Iteration...)
+	}
+}
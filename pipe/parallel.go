@@ -0,0 +1,99 @@
+// Copyright (c) 2020 Aritz Olea
+// This file is part of Simplepipe <https://github.com/aritzz/simplepipe>
+//
+// Simplepipe is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Simplepipe is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Simplepipe.  If not, see <https://www.gnu.org/licenses/>.
+
+package pipe
+
+import (
+	"context"
+	"sync"
+
+	"github.com/aritzz/simplepipe/data"
+)
+
+// execStepParallel Execute every step of a parallel block at the same time.
+// Each step runs on a fork of the state; results and assignments are
+// merged in block order, so the last step in the block wins on conflicts.
+// Steps start in block order as slots get free. Unless the block is
+// waitall, the first failure cancels the other steps and is the error
+// returned, steps that didn't start by then are skipped
+func execStepParallel(state *execState, execstep data.PipelineExecution) error {
+	var wg sync.WaitGroup
+	var failOnce sync.Once
+	var failErr error
+	var slots chan struct{}
+
+	ctx, cancel := context.WithCancel(state.ctx)
+	defer cancel()
+
+	if execstep.Parallel.Max > 0 {
+		slots = make(chan struct{}, execstep.Parallel.Max)
+	}
+
+	state.runner.logger.Println("Running", len(execstep.Body), "step(s) in parallel")
+
+	branches := make([]*execState, len(execstep.Body))
+	errs := make([]error, len(execstep.Body))
+	for i, execItem := range execstep.Body {
+		branches[i] = state.fork(ctx)
+		if slots != nil {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+			}
+		}
+		if ctx.Err() != nil {
+			skipBlock(branches[i], data.PipelineBlock{execItem})
+			errs[i] = contextError(ctx)
+			failOnce.Do(func() {
+				failErr = errs[i]
+			})
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, execItem data.PipelineExecution) {
+			defer wg.Done()
+			if slots != nil {
+				defer func() { <-slots }()
+			}
+			if errs[i] = execBlock(branches[i], data.PipelineBlock{execItem}); errs[i] == nil {
+				return
+			}
+			failOnce.Do(func() {
+				failErr = errs[i]
+				if !execstep.Parallel.WaitAll {
+					cancel()
+				}
+			})
+		}(i, execItem)
+	}
+	wg.Wait()
+
+	for _, branch := range branches {
+		state.merge(branch)
+	}
+
+	// With waitall, report the first failing step in block order
+	if execstep.Parallel.WaitAll {
+		for _, err := range errs {
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return failErr
+}
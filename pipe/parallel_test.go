@@ -0,0 +1,86 @@
+// Copyright (c) 2020 Aritz Olea
+// This file is part of Simplepipe <https://github.com/aritzz/simplepipe>
+//
+// Simplepipe is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Simplepipe is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Simplepipe.  If not, see <https://www.gnu.org/licenses/>.
+
+package pipe_test
+
+import (
+	"testing"
+
+	"github.com/aritzz/simplepipe/load"
+	"github.com/aritzz/simplepipe/pipe"
+)
+
+// TestParallelOrder Check that steps with max 1 run in block order
+func TestParallelOrder(t *testing.T) {
+	pipeline, err := load.ParseString("test.pipe", `pipeline order
+begin
+  (echo 0)
+  parallel max 1
+    (echo 1)
+    sh (sleep 0.01; echo 2)
+    (echo 3)
+    (echo 4)
+  endparallel
+end
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for run := 0; run < 10; run++ {
+		result, err := pipe.NewRunner().Run(pipeline)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 2; i < len(result.ExecStep); i++ {
+			if result.ExecStep[i].Start.Before(result.ExecStep[i-1].End) {
+				t.Fatalf("step %d started before step %d ended: %+v", i, i-1, result.ExecStep)
+			}
+		}
+	}
+}
+
+// TestParallelSkipped Check that steps that never start are reported as skipped
+func TestParallelSkipped(t *testing.T) {
+	pipeline, err := load.ParseString("test.pipe", `pipeline skipped
+begin
+  parallel max 1
+    (false)
+    (echo two)
+    (echo three)
+  endparallel
+end
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := pipe.NewRunner().Run(pipeline)
+	if err == nil {
+		t.Fatal("want an error")
+	}
+	if len(result.ExecStep) != 3 {
+		t.Fatalf("got %d steps, want 3: %+v", len(result.ExecStep), result.ExecStep)
+	}
+	if result.ExecStep[0].Command != "false" || result.ExecStep[0].Skipped {
+		t.Errorf("first step: %+v", result.ExecStep[0])
+	}
+	for _, step := range result.ExecStep[1:] {
+		if !step.Skipped {
+			t.Errorf("step not skipped: %+v", step)
+		}
+	}
+}
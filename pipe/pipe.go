@@ -17,219 +17,290 @@
 package pipe
 
 import (
-	"errors"
+	"context"
+	"io"
 	"log"
 	"os"
+	"os/exec"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/aritzz/simplepipe/data"
 )
 
-var loggerfile *os.File
-
 // LoadInput Loads an slice of inputs to a pipeline data object
 func LoadInput(pipeline *data.Pipeline, input []string) {
 
 	for i, _ := range pipeline.Input {
-		if i > len(input) {
+		if i >= len(input) {
 			break
 		}
 
 		pipeline.Input[i].Value = input[i]
+		pipeline.Input[i].Source = data.SOURCE_ARGS
 	}
 
 }
 
-// ExecutePipeline Executes a pipeline
+// ExecutePipeline Executes a pipeline, logging to the standard error
+// or to the logredirect file
 func ExecutePipeline(pipeline data.Pipeline, logredirect string) (data.PipelineResult, error) {
-	var pipeline_ret data.PipelineResult
-	var err_ret error
+	return ExecutePipelineContext(context.Background(), pipeline, logredirect)
+}
 
-	// Exec time
-	start_time := time.Now()
+// ExecutePipelineContext Executes a pipeline, running steps are stopped when ctx ends
+func ExecutePipelineContext(ctx context.Context, pipeline data.Pipeline, logredirect string) (data.PipelineResult, error) {
+	output := io.Writer(os.Stderr)
 
 	// Enable logging if needed
 	if len(strings.TrimSpace(logredirect)) > 0 {
-		if err := setLogRedirection(logredirect); err != nil {
-			return pipeline_ret, err
+		loggerfile, err := OpenLogFile(logredirect)
+		if err != nil {
+			return data.PipelineResult{}, err
 		}
 		defer loggerfile.Close()
+		output = loggerfile
 	}
 
-	log.Println("Starting pipeline " + pipeline.Name)
+	runner := NewRunner(WithLogger(log.New(output, "", log.LstdFlags)), WithContext(ctx))
+	return runner.Run(pipeline)
+}
+
+// execBlock Execute every step in a block, stops on the first error
+func execBlock(state *execState, block data.PipelineBlock) error {
+	var err_ret error
 
-	// Do execution
-	pipeline_ret = initVariables(pipeline)
-	for i, execItem := range pipeline.Execution {
-		log.Println("Running [", execItem.Command, "]")
-		pipeline_ret, err_ret = execStep(execItem, pipeline_ret, i)
-		if err_ret == nil {
-			log.Println("Finished in ", pipeline_ret.ExecStep[i].ExecTime)
-		} else {
-			log.Println("Execution error ", err_ret.Error())
+	for _, execItem := range block {
+		if state.ctx.Err() != nil {
+			err_ret = contextError(state.ctx)
+			break
+		}
+		if err_ret = execStep(state, execItem); err_ret != nil {
 			break
 		}
-
-	}
-
-	if err_ret == nil {
-		pipeline_ret, err_ret = getPipelineOutput(pipeline_ret, pipeline)
 	}
 
-	pipeline_ret.Time = time.Since(start_time)
-	log.Println("Pipeline execution time", pipeline_ret.Time)
-	return pipeline_ret, err_ret
+	return err_ret
 }
 
 // execStep Execute step in pipeline
-func execStep(execstep data.PipelineExecution, prevresult data.PipelineResult, i int) (data.PipelineResult, error) {
+func execStep(state *execState, execstep data.PipelineExecution) error {
 	var err_ret error
-	pipeline_ret := prevresult
+
+	// Block steps log their own steps
+	switch execstep.Type {
+	case data.TYPE_IF:
+		return execStepIf(state, execstep)
+	case data.TYPE_FOREACH:
+		return execStepForeach(state, execstep)
+	case data.TYPE_PARALLEL:
+		return execStepParallel(state, execstep)
+	case data.TYPE_TRY:
+		return execStepTry(state, execstep)
+	case data.TYPE_DEFER:
+		return execStepDefer(state, execstep)
+	}
+
+	state.runner.logger.Println("Running [", execstep.Command, "]")
 
 	switch execstep.Type {
 	case data.TYPE_ASSIGN:
-		return execStepAssign(execstep, prevresult, i)
+		err_ret = execStepAssign(state, execstep)
 	case data.TYPE_EXECASSIGN:
-		return execStepExecAssign(execstep, prevresult, i)
+		err_ret = execStepExecAssign(state, execstep)
 	case data.TYPE_EXEC:
-		return execStepExec(execstep, prevresult, i)
+		err_ret = execStepExec(state, execstep)
+	}
+
+	if err_ret == nil {
+		state.runner.logger.Println("Finished in ", state.steps[len(state.steps)-1].ExecTime)
+	} else {
+		state.runner.logger.Println("Execution error ", err_ret.Error())
 	}
 
-	return pipeline_ret, err_ret
+	// Ignored errors don't stop the pipeline, unless it has been cancelled
+	if err_ret != nil && execstep.IgnoreErrors && state.ctx.Err() == nil {
+		state.runner.logger.Println("Ignoring error")
+		state.steps[len(state.steps)-1].Ignored = true
+		err_ret = nil
+	}
+
+	return err_ret
 }
 
 // execStepAssign Execute step assignation
-func execStepAssign(execstep data.PipelineExecution, prevresult data.PipelineResult, i int) (data.PipelineResult, error) {
+func execStepAssign(state *execState, execstep data.PipelineExecution) error {
 	var err error
-	retresult := prevresult
+	var step data.PipelineResultExecStep
 
 	// Exec time
-	start_time := time.Now()
+	step.Start = state.runner.clock.Now()
 
 	// Get var
-	varcontent, err := getVarValue(prevresult, execstep.Command)
+	varcontent, err := getVarValue(state.vars, execstep.Command)
 	if err != nil {
 		goto stepEnd
 	}
 
-	// Assign
-	err = setVarValue(&retresult, execstep.Output, varcontent)
+	// Assign, a copy of a secret is secret too
+	err = setVarValue(state.vars, execstep.Output, varcontent)
 	if err != nil {
 		goto stepEnd
 	}
+	if state.vars.isSecret("$" + execstep.Command) {
+		state.vars.markSecret(execstep.Output)
+	}
 
 stepEnd:
-	retresult.ExecStep[i].ExecTime = time.Since(start_time)
-	retresult.ExecStep[i].Command = varcontent
-	if err != nil {
-		retresult.ExecStep[i].Error = err.Error()
-	}
-	return retresult, err
+	step.Command, _ = state.vars.masked(execstep.Command)
+	state.addStep(step, err)
+	return err
 }
 
-func execStepExecAssign(execstep data.PipelineExecution, prevresult data.PipelineResult, i int) (data.PipelineResult, error) {
+func execStepExecAssign(state *execState, execstep data.PipelineExecution) error {
 	var err error
-	retresult := prevresult
+	var step data.PipelineResultExecStep
+	result := commandResult{ExitCode: -1}
 
 	// Exec time
-	start_time := time.Now()
+	step.Start = state.runner.clock.Now()
 
 	// Replace values
-	commandexec := cmdReplaceVars(prevresult, execstep.Command)
+	commandexec, command, err := stepArgs(state.vars, execstep)
+	if err != nil {
+		goto execEnd
+	}
 
-	// Execute command
-	strOut, err := execCommandOutput(commandexec)
+	// Execute command, binding the exit code lets the pipeline handle failures
+	result, step.Attempts, err = execRetry(state, execstep, commandexec, commandEnv(state, execstep.Mode), len(execstep.Output) > 0)
+	if _, ok := err.(*exec.ExitError); ok && len(execstep.CodeOutput) > 0 {
+		err = nil
+	}
 	if err != nil {
 		goto execEnd
 	}
 
 	// Assign
-	err = setVarValue(&retresult, execstep.Output, strOut)
+	err = assignOutputs(state.vars, execstep, result)
 	if err != nil {
 		goto execEnd
 	}
 
 execEnd:
-	retresult.ExecStep[i].ExecTime = time.Since(start_time)
-	retresult.ExecStep[i].Command = commandexec
-	if err != nil {
-		retresult.ExecStep[i].Error = err.Error()
-	}
+	step.Command = command
+	step.Mode = execstep.Mode
+	setStepOutput(&step, result)
+	state.addStep(step, err)
 
-	return retresult, err
+	return err
 }
 
-func execStepExec(execstep data.PipelineExecution, prevresult data.PipelineResult, i int) (data.PipelineResult, error) {
+func execStepExec(state *execState, execstep data.PipelineExecution) error {
 	var err error
-	retresult := prevresult
+	var step data.PipelineResultExecStep
+	result := commandResult{ExitCode: -1}
 
 	// Exec time
-	start_time := time.Now()
+	step.Start = state.runner.clock.Now()
 
 	// Replace values
-	commandexec := cmdReplaceVars(prevresult, execstep.Command)
+	commandexec, command, err := stepArgs(state.vars, execstep)
+	if err != nil {
+		goto execEnd
+	}
 
 	// Execute command
-	err = execCommand(commandexec)
+	result, step.Attempts, err = execRetry(state, execstep, commandexec, commandEnv(state, execstep.Mode), false)
 	if err != nil {
 		goto execEnd
 	}
 
 execEnd:
-	retresult.ExecStep[i].ExecTime = time.Since(start_time)
-	retresult.ExecStep[i].Command = commandexec
-	if err != nil {
-		retresult.ExecStep[i].Error = err.Error()
+	step.Command = command
+	step.Mode = execstep.Mode
+	setStepOutput(&step, result)
+	state.addStep(step, err)
+
+	return err
+}
+
+// assignOutputs Assign stdout, stderr and exit code of a command to their variables
+func assignOutputs(vars *varStore, execstep data.PipelineExecution, result commandResult) error {
+	outputs := []struct {
+		name  string
+		value string
+	}{
+		{execstep.Output, result.Stdout},
+		{execstep.ErrOutput, strings.TrimSuffix(result.Stderr, "\n")},
+		{execstep.CodeOutput, strconv.Itoa(result.ExitCode)},
+	}
+
+	for _, output := range outputs {
+		if len(output.name) == 0 {
+			continue
+		}
+		if err := setVarValue(vars, output.name, output.value); err != nil {
+			return err
+		}
 	}
 
-	return retresult, err
+	return nil
 }
 
+// stepArgs Get arguments to run a step and the command as it is recorded,
+// with the values of secret variables masked. Shell commands are not
+// replaced here, the shell reads variables from its environment
+func stepArgs(vars *varStore, execstep data.PipelineExecution) ([]string, string, error) {
+	if execstep.Mode == data.MODE_SHELL {
+		return execstep.Args, execstep.Command, nil
+	}
+	args, err := cmdReplaceArgs(vars.get, execstep.Args)
+	if err != nil {
+		return nil, execstep.Command, err
+	}
+	shown, _ := cmdReplaceArgs(vars.masked, execstep.Args)
+	return args, commandString(shown), nil
+}
+
+// getPipelineOutput Get the values returned by the pipeline, from its final variables
 func getPipelineOutput(piperesult data.PipelineResult, pipeline data.Pipeline) (data.PipelineResult, error) {
 	ret_pipe := piperesult
-	var err error
-
-	if !pipeline.Output.Defined {
-		return ret_pipe, err
+	lookup := func(name string) (string, bool) {
+		value, ok := piperesult.Variables[name]
+		return value, ok
 	}
 
-	if val, ok := piperesult.Variables[pipeline.Output.Value]; ok {
-		ret_pipe.Output = val
-		return ret_pipe, err
+	for _, output := range pipeline.Output.Values {
+		value, err := data.Interpolate(output.Value, lookup)
+		if err != nil {
+			return ret_pipe, err
+		}
+		ret_pipe.Output = append(ret_pipe.Output, data.PipelineResultOutput{Name: output.Name, Value: value})
 	}
 
-	return ret_pipe, errors.New("return variable not found")
+	return ret_pipe, nil
 }
 
-func initVariables(pipeline data.Pipeline) data.PipelineResult {
-	pipeline_ret := data.PipelineResult{}
+// initVariables Get the result of a run that starts at start_time,
+// with its inputs and the initial variables
+func initVariables(pipeline data.Pipeline, start_time time.Time) data.PipelineResult {
+	pipeline_ret := data.PipelineResult{Name: pipeline.Name, Start: start_time}
 	pipeline_ret.Variables = make(map[string]string)
 
 	for _, val := range pipeline.Input {
 		pipeline_ret.Variables[val.Name] = val.Value
+		pipeline_ret.Inputs = append(pipeline_ret.Inputs, data.PipelineResultInput{Name: val.Name, Value: val.Value, Source: val.Source, Secret: val.Secret})
 	}
 
 	for key, val := range pipeline.Declaration {
 		pipeline_ret.Variables[key] = val
 	}
 
-	for i := 0; i < len(pipeline.Execution); i++ {
-		stepnew := data.PipelineResultExecStep{}
-		pipeline_ret.ExecStep = append(pipeline_ret.ExecStep, stepnew)
-	}
-
 	return pipeline_ret
 }
 
-func setLogRedirection(filename string) error {
-	var err error
-	loggerfile, err = os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0766)
-	if err != nil {
-		return err
-	}
-
-	log.SetOutput(loggerfile)
-
-	return err
+// OpenLogFile Open a file to append logs to
+func OpenLogFile(filename string) (*os.File, error) {
+	return os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0766)
 }
@@ -0,0 +1,49 @@
+// Copyright (c) 2020 Aritz Olea
+// This file is part of Simplepipe <https://github.com/aritzz/simplepipe>
+//
+// Simplepipe is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Simplepipe is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Simplepipe.  If not, see <https://www.gnu.org/licenses/>.
+
+//go:build windows
+// +build windows
+
+package pipe
+
+import (
+	"os"
+	"os/exec"
+)
+
+// setProcessGroup Process groups are not used on Windows
+func setProcessGroup(cmd *exec.Cmd) {
+}
+
+// terminateProcessGroup There is no SIGTERM on Windows, the process is killed
+func terminateProcessGroup(cmd *exec.Cmd) {
+	cmd.Process.Kill()
+}
+
+// signalProcessGroup Signals can't be sent on Windows, the process is killed
+func signalProcessGroup(cmd *exec.Cmd, sig os.Signal) {
+	cmd.Process.Kill()
+}
+
+// killProcessGroup Kill the command process
+func killProcessGroup(cmd *exec.Cmd) {
+	cmd.Process.Kill()
+}
+
+// exitSignal There are no signals on Windows
+func exitSignal(state *os.ProcessState) string {
+	return ""
+}
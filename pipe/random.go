@@ -0,0 +1,54 @@
+// Copyright (c) 2020 Aritz Olea
+// This file is part of Simplepipe <https://github.com/aritzz/simplepipe>
+//
+// Simplepipe is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Simplepipe is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Simplepipe.  If not, see <https://www.gnu.org/licenses/>.
+
+package pipe
+
+import (
+	"crypto/rand"
+	"math/big"
+	"strings"
+
+	"github.com/aritzz/simplepipe/data"
+)
+
+// setRandomValues Generate the values of the rand declarations of a
+// pipeline for this run and set them in vars
+func setRandomValues(pipeline data.Pipeline, vars map[string]string) error {
+	for _, random := range pipeline.Random {
+		value, err := getRandomString(random.Length, random.Alphabet)
+		if err != nil {
+			return err
+		}
+		vars[random.Name] = value
+	}
+	return nil
+}
+
+// getRandomString Get a random string from a cryptographically secure source
+func getRandomString(length int, alphabet string) (string, error) {
+	var b strings.Builder
+	chars := []rune(alphabet)
+	max := big.NewInt(int64(len(chars)))
+
+	for i := 0; i < length; i++ {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b.WriteRune(chars[n.Int64()])
+	}
+	return b.String(), nil
+}
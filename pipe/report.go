@@ -0,0 +1,127 @@
+// Copyright (c) 2020 Aritz Olea
+// This file is part of Simplepipe <https://github.com/aritzz/simplepipe>
+//
+// Simplepipe is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Simplepipe is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Simplepipe.  If not, see <https://www.gnu.org/licenses/>.
+
+package pipe
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+
+	"github.com/aritzz/simplepipe/data"
+)
+
+// REPORT_VERSION is the version of the report schema. New fields can
+// be added to a version, it changes when a field is renamed or removed
+const REPORT_VERSION = 1
+
+// REDACTED replaces the values of secret inputs in reports
+// and in the recorded commands of steps
+const REDACTED = "********"
+
+// Report is the JSON report of a run, the result of the run with the
+// schema version and the error that stopped it
+type Report struct {
+	Version int    `json:"version"`
+	Error   string `json:"error,omitempty"`
+	data.PipelineResult
+}
+
+// NewReport Get the report of a run that ended with err. Secret inputs
+// and their variables are redacted, as is any text that is a secret value.
+// Step commands already have them masked, as they were recorded
+func NewReport(result data.PipelineResult, err error) Report {
+	var secrets []string
+	secretVars := make(map[string]bool)
+	for _, input := range result.Inputs {
+		if input.Secret && len(input.Value) > 0 {
+			secrets = append(secrets, input.Value)
+			secretVars[input.Name] = true
+		}
+	}
+	redact := func(text string) string {
+		for _, secret := range secrets {
+			if text == secret {
+				return REDACTED
+			}
+		}
+		return text
+	}
+
+	report := Report{Version: REPORT_VERSION, PipelineResult: result}
+	if err != nil {
+		report.Error = redact(err.Error())
+	}
+
+	report.Inputs = []data.PipelineResultInput{}
+	for _, input := range result.Inputs {
+		if secretVars[input.Name] {
+			input.Value = REDACTED
+		}
+		input.Value = redact(input.Value)
+		report.Inputs = append(report.Inputs, input)
+	}
+	report.Variables = make(map[string]string)
+	for name, value := range result.Variables {
+		if secretVars[name] {
+			value = REDACTED
+		}
+		report.Variables[name] = redact(value)
+	}
+	report.Output = []data.PipelineResultOutput{}
+	for _, output := range result.Output {
+		output.Value = redact(output.Value)
+		report.Output = append(report.Output, output)
+	}
+	report.ExecStep = redactSteps(result.ExecStep, redact)
+	report.Deferred = redactSteps(result.Deferred, redact)
+
+	return report
+}
+
+// WriteFile Write the report to a file as indented JSON
+func (report Report) WriteFile(filename string) error {
+	var content bytes.Buffer
+	encoder := json.NewEncoder(&content)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, content.Bytes(), 0644)
+}
+
+// redactSteps Get a copy of steps with redact applied to their texts
+func redactSteps(steps []data.PipelineResultExecStep, redact func(string) string) []data.PipelineResultExecStep {
+	redacted := []data.PipelineResultExecStep{}
+
+	for _, step := range steps {
+		step.Error = redact(step.Error)
+		step.Stdout = redact(step.Stdout)
+		step.Stderr = redact(step.Stderr)
+
+		attempts := step.Attempts
+		step.Attempts = nil
+		for _, attempt := range attempts {
+			attempt.Error = redact(attempt.Error)
+			attempt.Stderr = redact(attempt.Stderr)
+			step.Attempts = append(step.Attempts, attempt)
+		}
+		redacted = append(redacted, step)
+	}
+
+	return redacted
+}
@@ -0,0 +1,89 @@
+// Copyright (c) 2020 Aritz Olea
+// This file is part of Simplepipe <https://github.com/aritzz/simplepipe>
+//
+// Simplepipe is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Simplepipe is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Simplepipe.  If not, see <https://www.gnu.org/licenses/>.
+
+package pipe_test
+
+import (
+	"testing"
+
+	"github.com/aritzz/simplepipe/load"
+	"github.com/aritzz/simplepipe/pipe"
+)
+
+// TestReportRedaction Check that secret values are masked in the
+// recorded commands and redacted in the report, and nothing else is
+func TestReportRedaction(t *testing.T) {
+	pipeline, err := load.ParseString("test.pipe", `pipeline secrets
+  read token "api token" secret
+  read count
+  use copy
+  use out
+begin
+  (echo -H $token --count=$count)
+  copy = token
+  foreach t in $copy
+    (echo $t)
+  endfor
+  foreach c in $count
+    (echo $c)
+  endfor
+  out = (seq 1 $count)
+  defer (echo x$token)
+  (false $token)
+end out
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = pipe.SetInput(&pipeline, []string{"1", "2"}); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := pipe.NewRunner().Run(pipeline)
+	if err == nil {
+		t.Fatal("the pipeline didn't fail")
+	}
+	want := []string{
+		"echo -H " + pipe.REDACTED + " --count=2",
+		pipe.REDACTED,
+		"echo " + pipe.REDACTED,
+		"echo 2",
+		"seq 1 2",
+		"false " + pipe.REDACTED,
+	}
+	if len(result.ExecStep) != len(want) {
+		t.Fatalf("got %d steps, want %d: %+v", len(result.ExecStep), len(want), result.ExecStep)
+	}
+	for i, step := range result.ExecStep {
+		if step.Command != want[i] {
+			t.Errorf("step %d: got command %q, want %q", i+1, step.Command, want[i])
+		}
+	}
+	if len(result.Deferred) != 1 || result.Deferred[0].Command != "echo x"+pipe.REDACTED {
+		t.Errorf("got deferred %+v", result.Deferred)
+	}
+
+	report := pipe.NewReport(result, err)
+	if report.Inputs[0].Value != pipe.REDACTED || report.Variables["token"] != pipe.REDACTED || report.Variables["copy"] != pipe.REDACTED {
+		t.Errorf("secret input not redacted: %+v %v", report.Inputs, report.Variables)
+	}
+	if report.Inputs[1].Value != "2" || report.Variables["count"] != "2" || report.Variables["out"] != "1\n2" {
+		t.Errorf("other values redacted: %+v %v", report.Inputs, report.Variables)
+	}
+	if result.Variables["token"] != "1" {
+		t.Errorf("result changed by the report")
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2020 Aritz Olea
+// This file is part of Simplepipe <https://github.com/aritzz/simplepipe>
+//
+// Simplepipe is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Simplepipe is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Simplepipe.  If not, see <https://www.gnu.org/licenses/>.
+
+package pipe
+
+import (
+	"time"
+
+	"github.com/aritzz/simplepipe/data"
+)
+
+// execRetry Run a step command as many times as its retry policy allows,
+// capturing its stdout if set. Returns the result of the last attempt and
+// the details of every attempt
+func execRetry(state *execState, execstep data.PipelineExecution, commandexec []string, env []string, stdout bool) (commandResult, []data.PipelineResultAttempt, error) {
+	var attempts []data.PipelineResultAttempt
+	output := commandOutput{Number: state.counter.next(), Command: execstep.Command, Stdout: stdout, Stderr: len(execstep.ErrOutput) > 0}
+
+	for i := 0; ; i++ {
+		var result commandResult
+		var err error
+
+		start_time := state.runner.clock.Now()
+		result, err = runCommand(state, execstep.Timeout, commandexec, env, output)
+
+		attempt := data.PipelineResultAttempt{ExitCode: result.ExitCode, Stderr: tail(result.Stderr, STDERR_TAIL), ExecTime: state.runner.clock.Now().Sub(start_time)}
+		if err != nil {
+			attempt.Error = err.Error()
+			attempt.ErrorKind = errorKind(err)
+		}
+		attempts = append(attempts, attempt)
+
+		if err == nil || !shouldRetry(state, execstep.Retry, i, result) {
+			return result, attempts, err
+		}
+
+		delay := retryDelay(*execstep.Retry, i)
+		state.runner.logger.Println("Attempt", i+1, "failed:", err.Error(), "- retrying in", delay)
+		select {
+		case <-state.runner.clock.After(delay):
+		case <-state.ctx.Done():
+			return result, attempts, contextError(state.ctx)
+		}
+	}
+}
+
+// shouldRetry Check if a failed attempt must be retried. Cancelled
+// pipelines and exit codes not listed in the policy are not retried
+func shouldRetry(state *execState, retry *data.PipelineRetry, i int, result commandResult) bool {
+	if retry == nil || i >= retry.Retries || state.ctx.Err() != nil {
+		return false
+	}
+	if len(retry.ExitCodes) == 0 {
+		return true
+	}
+
+	for _, code := range retry.ExitCodes {
+		if code == result.ExitCode {
+			return true
+		}
+	}
+	return false
+}
+
+// retryDelay Get the time to wait after a failed attempt
+func retryDelay(retry data.PipelineRetry, i int) time.Duration {
+	switch retry.Backoff {
+	case data.BACKOFF_FIXED:
+		return retry.MinDelay
+	case data.BACKOFF_EXPONENTIAL:
+		delay := retry.MinDelay
+		for j := 0; j < i && delay < retry.MaxDelay; j++ {
+			delay *= 2
+		}
+		if delay > retry.MaxDelay {
+			delay = retry.MaxDelay
+		}
+		return delay
+	}
+
+	return 0
+}
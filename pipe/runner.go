@@ -0,0 +1,300 @@
+// Copyright (c) 2020 Aritz Olea
+// This file is part of Simplepipe <https://github.com/aritzz/simplepipe>
+//
+// Simplepipe is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Simplepipe is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Simplepipe.  If not, see <https://www.gnu.org/licenses/>.
+
+package pipe
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/aritzz/simplepipe/data"
+)
+
+// Runner runs pipelines with its own logger, output writers, working
+// directory, environment, clock and context. Its settings never change
+// once it is created, so it can run pipelines from many goroutines at once.
+// Writes to stdout and stderr share one lock, they can be the same writer
+type Runner struct {
+	logger       *log.Logger
+	stdout       io.Writer
+	stderr       io.Writer
+	outputMutex  sync.Mutex
+	dir          string
+	env          []string
+	verbose      bool
+	logOutput    bool
+	captureLimit int64
+	clock        Clock
+	ctx          context.Context
+	interrupt    *Interrupt
+}
+
+// Option is a setting of a Runner, see NewRunner
+type Option func(*Runner)
+
+// Clock is the time source of a Runner, used for step timestamps,
+// execution times and retry delays
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// systemClock Clock of the system
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// NewRunner Create a runner. By default it logs nothing, discards the
+// output of commands, runs them in the current directory with the
+// environment of the process, captures up to CAPTURE_LIMIT bytes for
+// a variable, and uses the system clock and a background context
+func NewRunner(options ...Option) *Runner {
+	runner := &Runner{
+		logger:       log.New(ioutil.Discard, "", 0),
+		captureLimit: CAPTURE_LIMIT,
+		clock:        systemClock{},
+		ctx:          context.Background(),
+	}
+	for _, option := range options {
+		option(runner)
+	}
+
+	return runner
+}
+
+// WithLogger Log the progress of pipelines to logger
+func WithLogger(logger *log.Logger) Option {
+	return func(runner *Runner) {
+		runner.logger = logger
+	}
+}
+
+// WithStdout Stream the standard output of commands to w as it is
+// written, every line prefixed with its step number. Output assigned
+// to variables is not streamed, unless the runner is verbose
+func WithStdout(w io.Writer) Option {
+	return func(runner *Runner) {
+		runner.stdout = &syncWriter{mutex: &runner.outputMutex, w: w}
+	}
+}
+
+// WithStderr Stream the standard error of commands to w, as WithStdout does
+func WithStderr(w io.Writer) Option {
+	return func(runner *Runner) {
+		runner.stderr = &syncWriter{mutex: &runner.outputMutex, w: w}
+	}
+}
+
+// WithVerbose Stream output assigned to variables too
+func WithVerbose() Option {
+	return func(runner *Runner) {
+		runner.verbose = true
+	}
+}
+
+// WithOutputLogging Log every line of output of commands, assigned or not
+func WithOutputLogging() Option {
+	return func(runner *Runner) {
+		runner.logOutput = true
+	}
+}
+
+// WithCaptureLimit Fail steps that assign more than limit bytes of output
+// to a variable. Output that is not assigned is never held whole
+func WithCaptureLimit(limit int64) Option {
+	return func(runner *Runner) {
+		runner.captureLimit = limit
+	}
+}
+
+// WithDir Run commands and expand foreach globs in dir
+func WithDir(dir string) Option {
+	return func(runner *Runner) {
+		runner.dir = dir
+	}
+}
+
+// WithEnv Run commands with env, a list of NAME=value, instead of
+// the environment of the process
+func WithEnv(env []string) Option {
+	return func(runner *Runner) {
+		runner.env = append([]string{}, env...)
+	}
+}
+
+// WithClock Use clock instead of the system clock
+func WithClock(clock Clock) Option {
+	return func(runner *Runner) {
+		runner.clock = clock
+	}
+}
+
+// WithContext Stop pipelines run by Run when ctx ends
+func WithContext(ctx context.Context) Option {
+	return func(runner *Runner) {
+		runner.ctx = ctx
+	}
+}
+
+// WithInterrupt Stop pipelines when interrupt gets a signal,
+// whatever context they run on
+func WithInterrupt(interrupt *Interrupt) Option {
+	return func(runner *Runner) {
+		runner.interrupt = interrupt
+	}
+}
+
+// Run Execute a pipeline, running steps are stopped when the runner context ends
+func (runner *Runner) Run(pipeline data.Pipeline) (data.PipelineResult, error) {
+	return runner.RunContext(runner.ctx, pipeline)
+}
+
+// RunContext Execute a pipeline, running steps are stopped when ctx ends.
+// The runner context is not used. If the run is cancelled, the result
+// has the steps run so far and is marked as Cancelled
+func (runner *Runner) RunContext(ctx context.Context, pipeline data.Pipeline) (data.PipelineResult, error) {
+	var pipeline_ret data.PipelineResult
+	var err_ret error
+
+	// Exec time
+	start_time := runner.clock.Now()
+	pipeline_ret.Name = pipeline.Name
+	pipeline_ret.Start = start_time
+
+	runner.logger.Println("Starting pipeline " + pipeline.Name)
+
+	// Do execution
+	ctx, cancel := watchContext(ctx, runner.interrupt.interruptedChan())
+	defer cancel()
+	if pipeline.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, pipeline.Timeout)
+		defer cancel()
+	}
+	// Inputs are checked before any step runs
+	if pipeline.Input, err_ret = checkInput(pipeline.Input, runner.dir); err_ret != nil {
+		runner.logger.Println("Invalid input:", err_ret.Error())
+		return pipeline_ret, err_ret
+	}
+	pipeline_ret = initVariables(pipeline, start_time)
+	if err_ret = setRandomValues(pipeline, pipeline_ret.Variables); err_ret != nil {
+		return pipeline_ret, err_ret
+	}
+	tempdir, err_ret := createTempFiles(runner, pipeline, pipeline_ret.Variables)
+	if err_ret != nil {
+		return pipeline_ret, err_ret
+	}
+	state := newExecState(runner, ctx, pipeline_ret.Variables)
+	for _, input := range pipeline.Input {
+		if input.Secret {
+			state.vars.markSecret(input.Name)
+		}
+	}
+	if err_ret = execBlock(state, pipeline.Execution); err_ret != nil {
+		err_ret = stepError(err_ret, state.steps)
+	}
+	pipeline_ret.Deferred = execDeferred(state)
+	if pipeline.KeepTemp {
+		pipeline_ret.TempDir = tempdir
+	} else {
+		removeTempFiles(runner, tempdir)
+	}
+	pipeline_ret.Variables = state.vars.snapshot()
+	pipeline_ret.ExecStep = state.steps
+
+	if err_ret == nil {
+		pipeline_ret, err_ret = getPipelineOutput(pipeline_ret, pipeline)
+	}
+	if ctx.Err() == context.Canceled {
+		pipeline_ret.Cancelled = true
+		runner.logger.Println("Pipeline cancelled")
+	}
+
+	pipeline_ret.End = runner.clock.Now()
+	pipeline_ret.Time = pipeline_ret.End.Sub(start_time)
+	runner.logger.Println("Pipeline execution time", pipeline_ret.Time)
+	return pipeline_ret, err_ret
+}
+
+// environ Get the environment for commands
+func (runner *Runner) environ() []string {
+	if runner.env != nil {
+		return append([]string{}, runner.env...)
+	}
+	return os.Environ()
+}
+
+// glob Expand a file pattern, relative patterns are expanded in the
+// runner directory and their matches are kept relative to it
+func (runner *Runner) glob(pattern string) ([]string, error) {
+	if len(runner.dir) == 0 || filepath.IsAbs(pattern) {
+		return filepath.Glob(pattern)
+	}
+
+	matches, err := filepath.Glob(filepath.Join(runner.dir, pattern))
+	for i, match := range matches {
+		if rel, relErr := filepath.Rel(runner.dir, match); relErr == nil {
+			matches[i] = rel
+		}
+	}
+	return matches, err
+}
+
+// streams Get the writers that stream the output of a step line by line,
+// to w and to the logger. Captured output only goes to w if verbose
+func (runner *Runner) streams(number int, captured bool, w io.Writer) []*lineWriter {
+	var streams []*lineWriter
+	prefix := stepPrefix(number)
+
+	if w != nil && (!captured || runner.verbose) {
+		streams = append(streams, &lineWriter{emit: func(line string) {
+			fmt.Fprintln(w, prefix+line)
+		}})
+	}
+	if runner.logOutput {
+		streams = append(streams, &lineWriter{emit: func(line string) {
+			runner.logger.Println(prefix + line)
+		}})
+	}
+
+	return streams
+}
+
+// syncWriter Writer that can be shared by commands running at once,
+// writers of the same runner share mutex
+type syncWriter struct {
+	mutex *sync.Mutex
+	w     io.Writer
+}
+
+func (writer *syncWriter) Write(p []byte) (int, error) {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+	return writer.w.Write(p)
+}
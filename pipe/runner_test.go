@@ -0,0 +1,147 @@
+// Copyright (c) 2020 Aritz Olea
+// This file is part of Simplepipe <https://github.com/aritzz/simplepipe>
+//
+// Simplepipe is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Simplepipe is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Simplepipe.  If not, see <https://www.gnu.org/licenses/>.
+
+package pipe_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aritzz/simplepipe/load"
+	"github.com/aritzz/simplepipe/pipe"
+)
+
+// TestRunnerSharedOutput Check that one writer can get both stdout and
+// stderr of steps running at once, run with -race
+func TestRunnerSharedOutput(t *testing.T) {
+	pipeline, err := load.ParseString("test.pipe", `pipeline shared
+begin
+  parallel
+    sh (for i in 1 2 3 4 5; do echo out; echo err >&2; done)
+    sh (for i in 1 2 3 4 5; do echo err >&2; echo out; done)
+    sh (for i in 1 2 3 4 5; do echo out; echo err >&2; done)
+  endparallel
+end
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var output bytes.Buffer
+	runner := pipe.NewRunner(pipe.WithStdout(&output), pipe.WithStderr(&output))
+
+	var wait sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			if _, err := runner.Run(pipeline); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wait.Wait()
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	if len(lines) != 4*3*10 {
+		t.Fatalf("got %d lines, want %d:\n%s", len(lines), 4*3*10, output.String())
+	}
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "[step ") || !(strings.HasSuffix(line, "] out") || strings.HasSuffix(line, "] err")) {
+			t.Errorf("mixed line %q", line)
+		}
+	}
+}
+
+// TestRunnerDirFileInput Check that file inputs are looked up in the runner directory
+func TestRunnerDirFileInput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "simplepipe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err = ioutil.WriteFile(filepath.Join(dir, "in.txt"), []byte("content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	source := `pipeline files
+  read src file exists
+  use out
+begin
+  out = (cat $src)
+end out
+`
+	runner := pipe.NewRunner(pipe.WithDir(dir))
+	tests := []struct {
+		value string
+		ok    bool
+	}{
+		{"in.txt", true},
+		{filepath.Join(dir, "in.txt"), true},
+		{"runner_test.go", false},
+	}
+
+	for _, test := range tests {
+		pipeline, err := load.ParseString("test.pipe", source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = pipe.SetInput(&pipeline, []string{test.value}); err != nil {
+			t.Fatal(err)
+		}
+		result, err := runner.Run(pipeline)
+		if test.ok && (err != nil || len(result.Output) != 1 || result.Output[0].Value != "content") {
+			t.Errorf("%s: got %v, %v", test.value, result.Output, err)
+		}
+		if _, isInput := err.(*pipe.InputError); !test.ok && !isInput {
+			t.Errorf("%s: got %v, want an input error", test.value, err)
+		}
+	}
+}
+
+// TestRunnerRandomPerRun Check that rand values are generated on every run
+func TestRunnerRandomPerRun(t *testing.T) {
+	pipeline, err := load.ParseString("test.pipe", `pipeline random
+  rand id length 32 alphabet "ab"
+begin
+end id
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runner := pipe.NewRunner()
+	values := make(map[string]bool)
+	for i := 0; i < 3; i++ {
+		result, err := runner.Run(pipeline)
+		if err != nil {
+			t.Fatal(err)
+		}
+		value := result.Output[0].Value
+		if len(value) != 32 || strings.Trim(value, "ab") != "" {
+			t.Errorf("got %q, want 32 of a and b", value)
+		}
+		values[value] = true
+	}
+	if len(values) != 3 {
+		t.Errorf("got %d different values in 3 runs", len(values))
+	}
+}
@@ -0,0 +1,257 @@
+// Copyright (c) 2020 Aritz Olea
+// This file is part of Simplepipe <https://github.com/aritzz/simplepipe>
+//
+// Simplepipe is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Simplepipe is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Simplepipe.  If not, see <https://www.gnu.org/licenses/>.
+
+package pipe
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aritzz/simplepipe/data"
+)
+
+// Inputs take their value from the first source that has it, in this
+// order: command line arguments (SetInput), an input file (SetInputFile),
+// an env file (SetEnvFile), the environment variable or stdin named in
+// the read declaration (SetInputEnv) and the default value
+
+// SetInputFile Set inputs not given yet from a JSON or YAML file with
+// one value per input. Keys that are not inputs are an error
+func SetInputFile(pipeline *data.Pipeline, filename string) error {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+
+	var values map[string]string
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".json":
+		values, err = parseJSONInput(content)
+	case ".yaml", ".yml":
+		values, err = parseYAMLInput(content)
+	default:
+		return errors.New("Unknown input file format, use .json, .yaml or .yml: " + filename)
+	}
+	if err != nil {
+		return errors.New(filename + ": " + err.Error())
+	}
+
+	var problems []string
+	for _, name := range sortedKeys(values) {
+		input := findInput(pipeline.Input, name)
+		if input == nil {
+			problems = append(problems, "Unknown input "+name+" in "+filename)
+			continue
+		}
+		setInputValue(input, values[name], data.SOURCE_INPUT_FILE)
+	}
+
+	if len(problems) > 0 {
+		return &InputError{Problems: problems}
+	}
+	return nil
+}
+
+// SetEnvFile Set inputs not given yet from a .env file with NAME=value
+// lines. Names that are not inputs are ignored, env files are often shared
+func SetEnvFile(pipeline *data.Pipeline, filename string) error {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+
+	values, err := parseEnvFile(string(content))
+	if err != nil {
+		return errors.New(filename + ": " + err.Error())
+	}
+	for name, value := range values {
+		if input := findInput(pipeline.Input, name); input != nil {
+			setInputValue(input, value, data.SOURCE_ENV_FILE)
+		}
+	}
+
+	return nil
+}
+
+// SetInputEnv Set inputs not given yet that are read from an environment
+// variable or from stdin. stdin can be nil to leave those inputs unset
+func SetInputEnv(pipeline *data.Pipeline, stdin io.Reader) error {
+	for i := range pipeline.Input {
+		input := &pipeline.Input[i]
+		if input.Source != data.SOURCE_NONE {
+			continue
+		}
+		if value, ok := os.LookupEnv(input.Env); ok && len(input.Env) > 0 {
+			setInputValue(input, value, data.SOURCE_ENV)
+		}
+		if input.Stdin && stdin != nil {
+			content, err := ioutil.ReadAll(stdin)
+			if err != nil {
+				return err
+			}
+			setInputValue(input, string(content), data.SOURCE_STDIN)
+		}
+	}
+
+	return nil
+}
+
+// setInputValue Set the value of an input if it has not been given yet
+func setInputValue(input *data.PipelineInput, value string, source data.InputSource) {
+	if input.Source == data.SOURCE_NONE {
+		input.Value = value
+		input.Source = source
+	}
+}
+
+// parseJSONInput Parse a JSON object of input values, values can be
+// strings, numbers or booleans
+func parseJSONInput(content []byte) (map[string]string, error) {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(content, &raw); err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string)
+	for name, value := range raw {
+		switch value := value.(type) {
+		case string:
+			values[name] = value
+		case float64:
+			values[name] = strconv.FormatFloat(value, 'f', -1, 64)
+		case bool:
+			values[name] = strconv.FormatBool(value)
+		default:
+			return nil, errors.New("Invalid value for " + name + ", only strings, numbers and booleans are allowed")
+		}
+	}
+
+	return values, nil
+}
+
+// parseYAMLInput Parse a flat YAML mapping of input values. Only
+// name: value lines are allowed, with plain, single or double quoted values
+func parseYAMLInput(content []byte) (map[string]string, error) {
+	values := make(map[string]string)
+
+	for i, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		trimmed := strings.TrimSpace(line)
+		if len(trimmed) == 0 || trimmed[0] == '#' || trimmed == "---" {
+			continue
+		}
+
+		colon := strings.Index(line, ":")
+		if line[0] == ' ' || line[0] == '\t' || colon <= 0 {
+			return nil, errors.New("line " + strconv.Itoa(i+1) + ": only name: value lines are allowed")
+		}
+
+		value, err := parseQuoted(strings.TrimSpace(line[colon+1:]), true)
+		if err != nil {
+			return nil, errors.New("line " + strconv.Itoa(i+1) + ": " + err.Error())
+		}
+		values[strings.TrimSpace(line[:colon])] = value
+	}
+
+	return values, nil
+}
+
+// parseEnvFile Parse NAME=value lines of an env file, with an optional
+// export before the name and plain, single or double quoted values
+func parseEnvFile(content string) (map[string]string, error) {
+	values := make(map[string]string)
+
+	for i, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		line = strings.TrimSpace(strings.TrimPrefix(line, "export "))
+
+		eq := strings.Index(line, "=")
+		if eq <= 0 {
+			return nil, errors.New("line " + strconv.Itoa(i+1) + ": only NAME=value lines are allowed")
+		}
+
+		value, err := parseQuoted(strings.TrimSpace(line[eq+1:]), false)
+		if err != nil {
+			return nil, errors.New("line " + strconv.Itoa(i+1) + ": " + err.Error())
+		}
+		values[strings.TrimSpace(line[:eq])] = value
+	}
+
+	return values, nil
+}
+
+// parseQuoted Parse a value that may be quoted. Double quotes allow
+// \" \\ \n and \t escapes, plain values end at a # comment. YAML values
+// that are null or ~ are empty
+func parseQuoted(text string, yaml bool) (string, error) {
+	switch {
+	case strings.HasPrefix(text, "'"):
+		end := strings.LastIndex(text, "'")
+		if end == 0 {
+			return "", errors.New("unterminated single quote")
+		}
+		return strings.ReplaceAll(text[1:end], "''", "'"), nil
+	case strings.HasPrefix(text, "\""):
+		var value strings.Builder
+		for i := 1; i < len(text); i++ {
+			switch {
+			case text[i] == '"':
+				return value.String(), nil
+			case text[i] == '\\' && i+1 < len(text):
+				i++
+				switch text[i] {
+				case 'n':
+					value.WriteByte('\n')
+				case 't':
+					value.WriteByte('\t')
+				default:
+					value.WriteByte(text[i])
+				}
+			default:
+				value.WriteByte(text[i])
+			}
+		}
+		return "", errors.New("unterminated double quote")
+	}
+
+	if comment := strings.Index(text, " #"); comment >= 0 {
+		text = strings.TrimSpace(text[:comment])
+	}
+	if yaml && (text == "null" || text == "~") {
+		return "", nil
+	}
+	return text, nil
+}
+
+// sortedKeys Get the keys of a map in order
+func sortedKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,62 @@
+// Copyright (c) 2020 Aritz Olea
+// This file is part of Simplepipe <https://github.com/aritzz/simplepipe>
+//
+// Simplepipe is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Simplepipe is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Simplepipe.  If not, see <https://www.gnu.org/licenses/>.
+
+package pipe
+
+import (
+	"context"
+
+	"github.com/aritzz/simplepipe/data"
+)
+
+// execState State of a running pipeline. Parallel branches run on
+// their own state, forked from the parent one
+type execState struct {
+	runner   *Runner
+	counter  *stepCounter
+	ctx      context.Context
+	vars     *varStore
+	steps    []data.PipelineResultExecStep
+	deferred []deferredStep
+}
+
+// newExecState Create the state for a pipeline run
+func newExecState(runner *Runner, ctx context.Context, values map[string]string) *execState {
+	return &execState{runner: runner, counter: &stepCounter{}, ctx: ctx, vars: newVarStore(values)}
+}
+
+// fork Get a state for a parallel branch
+func (state *execState) fork(ctx context.Context) *execState {
+	return &execState{runner: state.runner, counter: state.counter, ctx: ctx, vars: state.vars.fork()}
+}
+
+// merge Add variables and step results of a finished branch
+func (state *execState) merge(child *execState) {
+	state.vars.merge(child.vars)
+	state.steps = append(state.steps, child.steps...)
+	state.deferred = append(state.deferred, child.deferred...)
+}
+
+// addStep Record a finished step, Start must be already set
+func (state *execState) addStep(step data.PipelineResultExecStep, err error) {
+	step.End = state.runner.clock.Now()
+	step.ExecTime = step.End.Sub(step.Start)
+	if err != nil {
+		step.Error = err.Error()
+		step.ErrorKind = errorKind(err)
+	}
+	state.steps = append(state.steps, step)
+}
@@ -0,0 +1,163 @@
+// Copyright (c) 2020 Aritz Olea
+// This file is part of Simplepipe <https://github.com/aritzz/simplepipe>
+//
+// Simplepipe is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Simplepipe is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Simplepipe.  If not, see <https://www.gnu.org/licenses/>.
+
+package pipe
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/aritzz/simplepipe/data"
+)
+
+// varStore Variables of a running pipeline, safe for concurrent use.
+// Parallel branches work on a fork of the store, their assignments
+// are merged back once every branch has finished. Variables marked
+// as secret are masked when commands are recorded
+type varStore struct {
+	mutex   sync.RWMutex
+	values  map[string]string
+	secret  map[string]bool
+	written []string
+}
+
+// newVarStore Create a store with a copy of the given variables
+func newVarStore(values map[string]string) *varStore {
+	store := &varStore{values: make(map[string]string), secret: make(map[string]bool)}
+	for key, value := range values {
+		store.values[key] = value
+	}
+	return store
+}
+
+// get Get a variable value
+func (store *varStore) get(name string) (string, bool) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	value, ok := store.values[name]
+	return value, ok
+}
+
+// masked Get a variable value as it is recorded, REDACTED if it is secret
+func (store *varStore) masked(name string) (string, bool) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	value, ok := store.values[name]
+	if ok && store.secret[name] {
+		value = REDACTED
+	}
+	return value, ok
+}
+
+// markSecret Mark a variable as secret, it stays secret when
+// it is assigned again
+func (store *varStore) markSecret(name string) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.secret[name] = true
+}
+
+// isSecret Check if a template reads a secret variable
+func (store *varStore) isSecret(template string) bool {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	names, _ := data.TemplateVars(template)
+	for _, name := range names {
+		if store.secret[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// set Assign a declared variable
+func (store *varStore) set(name string, value string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if _, exists := store.values[name]; !exists {
+		return errors.New("Variable " + name + " is not declared")
+	}
+	store.values[name] = value
+	store.written = append(store.written, name)
+
+	return nil
+}
+
+// declare Create a scoped variable, like a loop variable
+func (store *varStore) declare(name string, value string) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.values[name] = value
+}
+
+// remove Remove a scoped variable
+func (store *varStore) remove(name string) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	delete(store.values, name)
+	delete(store.secret, name)
+}
+
+// snapshot Get a copy of every variable
+func (store *varStore) snapshot() map[string]string {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	values := make(map[string]string)
+	for key, value := range store.values {
+		values[key] = value
+	}
+	return values
+}
+
+// fork Get a copy of the store for a parallel branch
+func (store *varStore) fork() *varStore {
+	fork := newVarStore(store.snapshot())
+	store.mutex.RLock()
+	for name := range store.secret {
+		fork.secret[name] = true
+	}
+	store.mutex.RUnlock()
+	return fork
+}
+
+// merge Apply assignments made in a fork, in the order they were made.
+// Scoped variables that no longer exist in the fork are left out
+func (store *varStore) merge(child *varStore) {
+	child.mutex.RLock()
+	defer child.mutex.RUnlock()
+
+	for _, name := range child.written {
+		value, ok := child.values[name]
+		if !ok {
+			continue
+		}
+		store.mutex.Lock()
+		if _, exists := store.values[name]; exists {
+			store.values[name] = value
+			store.secret[name] = store.secret[name] || child.secret[name]
+			store.written = append(store.written, name)
+		}
+		store.mutex.Unlock()
+	}
+}
@@ -0,0 +1,167 @@
+// Copyright (c) 2020 Aritz Olea
+// This file is part of Simplepipe <https://github.com/aritzz/simplepipe>
+//
+// Simplepipe is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Simplepipe is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Simplepipe.  If not, see <https://www.gnu.org/licenses/>.
+
+package pipe
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strconv"
+	"sync/atomic"
+)
+
+// LINE_LIMIT is the longest line streamed at once, longer
+// lines are split so they are never held in memory
+const LINE_LIMIT = 64 * 1024
+
+// CAPTURE_LIMIT is the default size limit of output assigned to variables
+const CAPTURE_LIMIT = 64 * 1024 * 1024
+
+// stepCounter Numbers the commands run by a pipeline, it is
+// shared by every state of a run
+type stepCounter struct {
+	last int32
+}
+
+func (counter *stepCounter) next() int {
+	return int(atomic.AddInt32(&counter.last, 1))
+}
+
+// stepPrefix Get the prefix of the streamed lines of a step
+func stepPrefix(number int) string {
+	return "[step " + strconv.Itoa(number) + "] "
+}
+
+// lineWriter Writer that calls emit with every complete line written,
+// the last line is emitted by Flush even without a line break
+type lineWriter struct {
+	emit    func(line string)
+	pending []byte
+}
+
+func (writer *lineWriter) Write(p []byte) (int, error) {
+	written := len(p)
+
+	for len(p) > 0 {
+		end := bytes.IndexByte(p, '\n')
+		if end < 0 {
+			room := LINE_LIMIT - len(writer.pending)
+			if len(p) < room {
+				writer.pending = append(writer.pending, p...)
+				break
+			}
+			writer.pending = append(writer.pending, p[:room]...)
+			p = p[room:]
+			writer.emitPending()
+			continue
+		}
+		writer.pending = append(writer.pending, p[:end]...)
+		p = p[end+1:]
+		writer.emitPending()
+	}
+
+	return written, nil
+}
+
+// Flush Emit the pending line, if any
+func (writer *lineWriter) Flush() {
+	if len(writer.pending) > 0 {
+		writer.emitPending()
+	}
+}
+
+func (writer *lineWriter) emitPending() {
+	writer.emit(string(bytes.TrimSuffix(writer.pending, []byte("\r"))))
+	writer.pending = writer.pending[:0]
+}
+
+// limitBuffer Writer that keeps everything written up to limit
+// bytes, and notes when more has been written
+type limitBuffer struct {
+	limit    int64
+	data     bytes.Buffer
+	exceeded bool
+}
+
+func (buffer *limitBuffer) Write(p []byte) (int, error) {
+	if room := buffer.limit - int64(buffer.data.Len()); int64(len(p)) > room {
+		buffer.exceeded = true
+		if room > 0 {
+			buffer.data.Write(p[:room])
+		}
+	} else {
+		buffer.data.Write(p)
+	}
+	return len(p), nil
+}
+
+func (buffer *limitBuffer) String() string {
+	return buffer.data.String()
+}
+
+// byteCounter Writer that counts the bytes written
+type byteCounter struct {
+	count int64
+}
+
+func (counter *byteCounter) Write(p []byte) (int, error) {
+	counter.count += int64(len(p))
+	return len(p), nil
+}
+
+// outputPipe Pipe the output of a command is copied from. Unlike the
+// pipes of exec.Cmd, waiting for the command doesn't wait for the copy,
+// so children that keep the pipe open can be left behind
+type outputPipe struct {
+	reader *os.File
+	writer *os.File
+	copied chan struct{}
+}
+
+// newOutputPipe Create a pipe that copies everything written to w
+func newOutputPipe(w io.Writer) (*outputPipe, error) {
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+
+	output := &outputPipe{reader: reader, writer: writer, copied: make(chan struct{})}
+	go func() {
+		io.Copy(w, reader)
+		reader.Close()
+		close(output.copied)
+	}()
+	return output, nil
+}
+
+// close Stop copying, whoever still has the pipe open
+func (output *outputPipe) close() {
+	output.writer.Close()
+	output.reader.Close()
+}
+
+// copyDone Get a channel that is closed once every pipe is copied
+func copyDone(pipes ...*outputPipe) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		for _, output := range pipes {
+			<-output.copied
+		}
+		close(done)
+	}()
+	return done
+}
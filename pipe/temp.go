@@ -0,0 +1,70 @@
+// Copyright (c) 2020 Aritz Olea
+// This file is part of Simplepipe <https://github.com/aritzz/simplepipe>
+//
+// Simplepipe is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Simplepipe is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Simplepipe.  If not, see <https://www.gnu.org/licenses/>.
+
+package pipe
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/aritzz/simplepipe/data"
+)
+
+// createTempFiles Create the temporary files and directories of a pipeline
+// in a private directory for this run, and set their paths in vars.
+// Returns the directory, empty if the pipeline has no temporary files
+func createTempFiles(runner *Runner, pipeline data.Pipeline, vars map[string]string) (string, error) {
+	if len(pipeline.Temp) == 0 {
+		return "", nil
+	}
+
+	// TempDir creates the directory with mode 0700
+	dir, err := ioutil.TempDir("", "simplepipe-"+pipeline.Name+"-")
+	if err != nil {
+		return "", err
+	}
+
+	for _, temp := range pipeline.Temp {
+		path := filepath.Join(dir, temp.Name+temp.Suffix)
+		if temp.Dir {
+			err = os.Mkdir(path, 0700)
+		} else {
+			var file *os.File
+			if file, err = os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600); err == nil {
+				err = file.Close()
+			}
+		}
+		if err != nil {
+			os.RemoveAll(dir)
+			return "", err
+		}
+		vars[temp.Name] = path
+	}
+
+	runner.logger.Println("Temporary files in", dir)
+	return dir, nil
+}
+
+// removeTempFiles Remove the temporary directory of a run
+func removeTempFiles(runner *Runner, dir string) {
+	if len(dir) == 0 {
+		return
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		runner.logger.Println("Error removing temporary files:", err.Error())
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2020 Aritz Olea
+// This file is part of Simplepipe <https://github.com/aritzz/simplepipe>
+//
+// Simplepipe is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Simplepipe is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Simplepipe.  If not, see <https://www.gnu.org/licenses/>.
+
+package pipe
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/aritzz/simplepipe/data"
+)
+
+// CLEANUP_TIMEOUT is the time cleanup steps get once the pipeline
+// has timed out or has been interrupted
+const CLEANUP_TIMEOUT = 30 * time.Second
+
+// execStepTry Execute a try block. If it fails the catch block handles the
+// error, unless the pipeline itself has timed out or been interrupted.
+// The finally block always runs, and errors it returns never hide
+// the original one
+func execStepTry(state *execState, execstep data.PipelineExecution) error {
+	first := len(state.steps)
+	err := execBlock(state, execstep.Body)
+
+	if err != nil && execstep.Try.HasCatch && state.ctx.Err() == nil {
+		state.runner.logger.Println("Caught error:", err.Error())
+		code := lastExitCode(state.steps[first:])
+		for i := first; i < len(state.steps); i++ {
+			state.steps[i].Ignored = state.steps[i].Ignored || len(state.steps[i].Error) > 0
+		}
+		err = execCatch(state, *execstep.Try, err, code)
+	}
+
+	if len(execstep.Try.Finally) > 0 {
+		if finallyErr := execCleanup(state, execstep.Try.Finally); err == nil {
+			err = finallyErr
+		}
+	}
+
+	return err
+}
+
+// execCatch Execute a catch block with the error variables in scope
+func execCatch(state *execState, try data.PipelineTry, caught error, code int) error {
+	if len(try.CatchMessage) > 0 {
+		state.vars.declare(try.CatchMessage, caught.Error())
+		defer state.vars.remove(try.CatchMessage)
+	}
+	if len(try.CatchCode) > 0 {
+		state.vars.declare(try.CatchCode, strconv.Itoa(code))
+		defer state.vars.remove(try.CatchCode)
+	}
+
+	return execBlock(state, try.Catch)
+}
+
+// execCleanup Execute a cleanup block. If the pipeline has already timed
+// out or been interrupted, it runs on its own context for CLEANUP_TIMEOUT
+func execCleanup(state *execState, block data.PipelineBlock) error {
+	ctx := state.ctx
+	cleanupctx, cancel := cleanupContext(state)
+	defer cancel()
+
+	state.ctx = cleanupctx
+	err := execBlock(state, block)
+	state.ctx = ctx
+
+	return err
+}
+
+// cleanupContext Get the context for cleanup steps, a new one limited
+// to CLEANUP_TIMEOUT if the state context has already ended. A second
+// interrupt signal ends it
+func cleanupContext(state *execState) (context.Context, context.CancelFunc) {
+	if state.ctx.Err() == nil {
+		return context.WithCancel(state.ctx)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), CLEANUP_TIMEOUT)
+	killctx, killcancel := watchContext(ctx, state.runner.interrupt.killedChan())
+	return killctx, func() {
+		killcancel()
+		cancel()
+	}
+}
+
+// lastExitCode Get the exit code of the last failed step
+func lastExitCode(steps []data.PipelineResultExecStep) int {
+	for i := len(steps) - 1; i >= 0; i-- {
+		if len(steps[i].Error) > 0 && !steps[i].Ignored {
+			return steps[i].ExitCode
+		}
+	}
+	return -1
+}
@@ -14,45 +14,46 @@
 // You should have received a copy of the GNU General Public License
 // along with Simplepipe.  If not, see <https://www.gnu.org/licenses/>.
 
-
 package pipe
 
-import(
-  "github.com/aritzz/simplepipe/data"
-  "errors"
-  "strings"
-)
+import (
+	"errors"
 
+	"github.com/aritzz/simplepipe/data"
+)
 
-func getVarValue(pipeline data.PipelineResult, variable string) (string, error) {
-  var err error
-  var_ret, recv := pipeline.Variables[variable]
+func getVarValue(vars *varStore, variable string) (string, error) {
+	var err error
+	var_ret, recv := vars.get(variable)
 
-  if !recv {
-    err = errors.New("Error getting variable "+variable)
-  }
+	if !recv {
+		err = errors.New("Error getting variable " + variable)
+	}
 
-  return var_ret, err
+	return var_ret, err
 }
 
-func setVarValue(pipeline *data.PipelineResult, variable string, value string) (error) {
-  _, exists := pipeline.Variables[variable]
-  if !exists {
-    return errors.New("Variable "+variable+" is not declared")
-  }
-
-  pipeline.Variables[variable] = value
+func setVarValue(vars *varStore, variable string, value string) error {
+	return vars.set(variable, value)
+}
 
-  return nil
+// cmdReplaceVars Replace variables in a command template
+func cmdReplaceVars(vars *varStore, command string) (string, error) {
+	return data.Interpolate(command, vars.get)
 }
 
-func cmdReplaceVars(pipeline data.PipelineResult, command string) (string) {
-  replaced := command
+// cmdReplaceArgs Replace variables in every argument, keeping each one as
+// a single argument. lookup gets the values, like varStore get or masked
+func cmdReplaceArgs(lookup func(string) (string, bool), args []string) ([]string, error) {
+	replaced := make([]string, len(args))
 
-  for key, value := range pipeline.Variables {
-    currentVar := "$"+key
-    replaced = strings.ReplaceAll(replaced, currentVar, value)
-  }
+	for i, arg := range args {
+		value, err := data.Interpolate(arg, lookup)
+		if err != nil {
+			return nil, err
+		}
+		replaced[i] = value
+	}
 
-  return replaced
+	return replaced, nil
 }
@@ -0,0 +1,96 @@
+// Copyright (c) 2020 Aritz Olea
+// This file is part of Simplepipe <https://github.com/aritzz/simplepipe>
+//
+// Simplepipe is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Simplepipe is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Simplepipe.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/aritzz/simplepipe/data"
+)
+
+// promptInputs Ask in the terminal for the inputs that are still missing.
+// Bad values are asked again, an empty answer keeps the default value
+func promptInputs(pipeline *data.Pipeline) {
+	reader := bufio.NewReader(os.Stdin)
+
+	for i := range pipeline.Input {
+		input := &pipeline.Input[i]
+		if input.Source != data.SOURCE_NONE {
+			continue
+		}
+
+		for {
+			fmt.Fprint(os.Stderr, promptText(*input))
+			var value string
+			var err error
+			if input.Secret {
+				value, err = readSecret(reader)
+			} else {
+				value, err = readLine(reader)
+			}
+
+			// Without more input, missing values are reported later
+			if err != nil {
+				fmt.Fprintln(os.Stderr)
+				return
+			}
+			if len(value) == 0 && input.HasDefault {
+				break
+			}
+			if len(value) == 0 {
+				fmt.Fprintln(os.Stderr, "A value is required for", input.Name)
+				continue
+			}
+			if err = input.CheckValue(value); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				continue
+			}
+
+			input.Value, input.Source = value, data.SOURCE_PROMPT
+			break
+		}
+	}
+}
+
+// promptText Get the question asked for an input
+func promptText(input data.PipelineInput) string {
+	text := input.Name
+	if len(input.Description) > 0 {
+		text = input.Description + " (" + input.Name + ")"
+	}
+
+	switch {
+	case input.HasDefault && input.Secret:
+		text += " [" + SECRET_MASK + "]"
+	case input.HasDefault:
+		text += " [" + input.Default + "]"
+	}
+	return text + ": "
+}
+
+// readLine Read a line, without the line break
+func readLine(reader *bufio.Reader) (string, error) {
+	line, err := reader.ReadString('\n')
+	if err == io.EOF && len(line) > 0 {
+		err = nil
+	}
+	return strings.TrimRight(line, "\r\n"), err
+}
@@ -0,0 +1,69 @@
+// Copyright (c) 2020 Aritz Olea
+// This file is part of Simplepipe <https://github.com/aritzz/simplepipe>
+//
+// Simplepipe is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Simplepipe is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Simplepipe.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aritzz/simplepipe/data"
+)
+
+// STDERR_LINES is how many stderr lines are shown for a failed step
+const STDERR_LINES = 10
+
+// printFailedSteps Print failed steps with the last lines of their stderr
+func printFailedSteps(pipeline data.PipelineResult) {
+	for _, el := range pipeline.ExecStep {
+		if len(el.Error) == 0 || el.Ignored {
+			continue
+		}
+		fmt.Fprintln(os.Stderr, "Step [", el.Command, "] failed:", el.Error)
+		for _, line := range tailLines(el.Stderr, STDERR_LINES) {
+			fmt.Fprintln(os.Stderr, "  |", line)
+		}
+	}
+}
+
+// printFailedDeferred Print failed deferred commands, they don't fail
+// the pipeline but must not go unnoticed
+func printFailedDeferred(pipeline data.PipelineResult) {
+	for _, el := range pipeline.Deferred {
+		if len(el.Error) == 0 {
+			continue
+		}
+		fmt.Fprintln(os.Stderr, "Deferred [", el.Command, "] failed:", el.Error)
+		for _, line := range tailLines(el.Stderr, STDERR_LINES) {
+			fmt.Fprintln(os.Stderr, "  |", line)
+		}
+	}
+}
+
+// tailLines Get the last lines of a text
+func tailLines(text string, count int) []string {
+	text = strings.TrimRight(text, "\n")
+	if len(text) == 0 {
+		return nil
+	}
+
+	lines := strings.Split(text, "\n")
+	if len(lines) > count {
+		lines = lines[len(lines)-count:]
+	}
+	return lines
+}
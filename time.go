@@ -22,6 +22,8 @@ import (
 	"github.com/aritzz/simplepipe/data"
 )
 
+const TIME_FORMAT = "15:04:05.000"
+
 // printExectimeGlobal Print global execution time from pipeline
 func printExectimeGlobal(pipeline data.PipelineResult) {
 	fmt.Println("Execution time:", pipeline.Time)
@@ -29,7 +31,26 @@ func printExectimeGlobal(pipeline data.PipelineResult) {
 
 // printExectimeFunction Print function execution time from pipeline
 func printExectimeFunction(pipeline data.PipelineResult) {
-	for _, el := range pipeline.ExecStep {
-		fmt.Println("Command [", el.Command, "] - Time [", el.ExecTime, "]")
+	printExectimeSteps("Command", pipeline.ExecStep)
+	printExectimeSteps("Deferred", pipeline.Deferred)
+}
+
+// printExectimeSteps Print execution time of a list of steps
+func printExectimeSteps(label string, steps []data.PipelineResultExecStep) {
+	for _, el := range steps {
+		iteration := ""
+		if len(el.Iteration) > 0 {
+			iteration = fmt.Sprint(" - Iteration ", el.Iteration)
+		}
+		if el.Skipped {
+			fmt.Println(label+" [", el.Command, "]"+iteration+" - Skipped")
+			continue
+		}
+		fmt.Println(label+" [", el.Command, "]"+iteration+" - Time [", el.ExecTime, "] - Start [", el.Start.Format(TIME_FORMAT), "] - End [", el.End.Format(TIME_FORMAT), "]")
+		if len(el.Attempts) > 1 {
+			for i, attempt := range el.Attempts {
+				fmt.Println("  Attempt [", i+1, "] - Exit code [", attempt.ExitCode, "] - Time [", attempt.ExecTime, "]")
+			}
+		}
 	}
 }
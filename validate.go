@@ -0,0 +1,89 @@
+// Copyright (c) 2020 Aritz Olea
+// This file is part of Simplepipe <https://github.com/aritzz/simplepipe>
+//
+// Simplepipe is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Simplepipe is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Simplepipe.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+
+	"github.com/aritzz/simplepipe/load"
+)
+
+// validateEntry is a diagnostic in the json output of validate
+type validateEntry struct {
+	File     string `json:"file"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// runValidate Run the validate subcommand, checking pipeline files
+// without running them. Returns the exit code, EXIT_LOAD if a file can't
+// be read and EXIT_FAILURE if anything is found in them, warnings
+// included unless -allow-warnings is set
+func runValidate(args []string) int {
+	flags := flag.NewFlagSet("validate", flag.ContinueOnError)
+	format := flags.String("format", "text", "output format: text or json")
+	allowWarnings := flags.Bool("allow-warnings", false, "only fail on errors")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: simplepipe validate [-format text|json] [-allow-warnings] file.pipe...")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return parseErrorCode(err)
+	}
+
+	if flags.NArg() == 0 || (*format != "text" && *format != "json") {
+		flags.Usage()
+		return EXIT_USAGE
+	}
+
+	failed, unreadable := false, false
+	entries := []validateEntry{}
+	for _, file := range flags.Args() {
+		diags, err := load.ValidateFile(file)
+		if err != nil {
+			diags = load.Diagnostics{{Message: err.Error()}}
+			diags[0].Pos.File = file
+			unreadable = true
+		}
+
+		for _, diag := range diags {
+			failed = failed || diag.Severity == load.SEVERITY_ERROR || !*allowWarnings
+			if *format == "text" {
+				fmt.Print(diag.Format())
+				continue
+			}
+			entries = append(entries, validateEntry{File: diag.Pos.File, Line: diag.Pos.Line, Column: diag.Pos.Column, Severity: diag.Severity.String(), Message: diag.Message})
+		}
+	}
+
+	if *format == "json" {
+		output, _ := json.MarshalIndent(entries, "", "  ")
+		fmt.Println(string(output))
+	}
+
+	switch {
+	case unreadable:
+		return EXIT_LOAD
+	case failed:
+		return EXIT_FAILURE
+	}
+	return EXIT_OK
+}